@@ -3,10 +3,60 @@ package cobra
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"unsafe"
 )
 
 type PositionalArgs func(cmd *Command, args []string) error
 
+// ArgsArity describes the positional arguments accepted by a validator of cobra.
+type ArgsArity struct {
+	// Min is the minimum number of arguments.
+	Min int
+	// Max is the maximum number of arguments, or -1 if there is none.
+	Max int
+	// OnlyValidArgs tells whether the arguments other than the ValidArgs of
+	// the command are rejected.
+	OnlyValidArgs bool
+}
+
+// argsArities holds the arity of the validators of cobra, by their closure:
+// a func value points to its closure, which is allocated for each validator
+// returned by MinimumNArgs and the others.
+var (
+	argsAritiesMu sync.Mutex
+	argsArities   = map[unsafe.Pointer]ArgsArity{
+		closureOf(NoArgs):        {Min: 0, Max: 0},
+		closureOf(ArbitraryArgs): {Min: 0, Max: -1},
+		closureOf(OnlyValidArgs): {Min: 0, Max: -1, OnlyValidArgs: true},
+	}
+)
+
+func closureOf(args PositionalArgs) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&args))
+}
+
+// withArity records the arity of the validator args.
+func withArity(args PositionalArgs, arity ArgsArity) PositionalArgs {
+	argsAritiesMu.Lock()
+	defer argsAritiesMu.Unlock()
+	argsArities[closureOf(args)] = arity
+	return args
+}
+
+// ArityOf returns the arguments accepted by args if it is one of the validators
+// of cobra, such as NoArgs or the validators returned by MinimumNArgs, and false
+// otherwise.  The validator is not called.
+func ArityOf(args PositionalArgs) (ArgsArity, bool) {
+	if args == nil {
+		return ArgsArity{}, false
+	}
+	argsAritiesMu.Lock()
+	defer argsAritiesMu.Unlock()
+	arity, ok := argsArities[closureOf(args)]
+	return arity, ok
+}
+
 // Legacy arg validation has the following behaviour:
 // - root commands with no subcommands can take arbitrary arguments
 // - root commands with subcommands will do subcommand validity checking
@@ -58,52 +108,56 @@ func ArbitraryArgs(cmd *Command, args []string) error {
 
 // MinimumNArgs returns an error if there is not at least N args.
 func MinimumNArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
+	return withArity(func(cmd *Command, args []string) error {
 		if len(args) < n {
 			return fmt.Errorf("requires at least %d arg(s), only received %d", n, len(args))
 		}
 		return nil
-	}
+	}, ArgsArity{Min: n, Max: -1})
 }
 
 // MaximumNArgs returns an error if there are more than N args.
 func MaximumNArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
+	return withArity(func(cmd *Command, args []string) error {
 		if len(args) > n {
 			return fmt.Errorf("accepts at most %d arg(s), received %d", n, len(args))
 		}
 		return nil
-	}
+	}, ArgsArity{Min: 0, Max: n})
 }
 
 // ExactArgs returns an error if there are not exactly n args.
 func ExactArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
-		if len(args) != n {
-			return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
-		}
-		return nil
+	return withArity(func(cmd *Command, args []string) error {
+		return exactArgs(n, args)
+	}, ArgsArity{Min: n, Max: n})
+}
+
+func exactArgs(n int, args []string) error {
+	if len(args) != n {
+		return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
 	}
+	return nil
 }
 
 // ExactValidArgs returns an error if
 // there are not exactly N positional args OR
 // there are any positional args that are not in the `ValidArgs` field of `Command`
 func ExactValidArgs(n int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
-		if err := ExactArgs(n)(cmd, args); err != nil {
+	return withArity(func(cmd *Command, args []string) error {
+		if err := exactArgs(n, args); err != nil {
 			return err
 		}
 		return OnlyValidArgs(cmd, args)
-	}
+	}, ArgsArity{Min: n, Max: n, OnlyValidArgs: true})
 }
 
 // RangeArgs returns an error if the number of args is not within the expected range.
 func RangeArgs(min int, max int) PositionalArgs {
-	return func(cmd *Command, args []string) error {
+	return withArity(func(cmd *Command, args []string) error {
 		if len(args) < min || len(args) > max {
 			return fmt.Errorf("accepts between %d and %d arg(s), received %d", min, max, len(args))
 		}
 		return nil
-	}
+	}, ArgsArity{Min: min, Max: max})
 }
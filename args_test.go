@@ -285,3 +285,42 @@ func TestChildTakesArgs(t *testing.T) {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 }
+
+func TestArityOf(t *testing.T) {
+	testcases := []struct {
+		args     PositionalArgs
+		expected ArgsArity
+	}{
+		{NoArgs, ArgsArity{Min: 0, Max: 0}},
+		{ArbitraryArgs, ArgsArity{Min: 0, Max: -1}},
+		{OnlyValidArgs, ArgsArity{Min: 0, Max: -1, OnlyValidArgs: true}},
+		{MinimumNArgs(2), ArgsArity{Min: 2, Max: -1}},
+		{MaximumNArgs(3), ArgsArity{Min: 0, Max: 3}},
+		{ExactArgs(1), ArgsArity{Min: 1, Max: 1}},
+		{ExactArgs(2), ArgsArity{Min: 2, Max: 2}},
+		{ExactValidArgs(1), ArgsArity{Min: 1, Max: 1, OnlyValidArgs: true}},
+		{RangeArgs(1, 4), ArgsArity{Min: 1, Max: 4}},
+	}
+	for _, tc := range testcases {
+		arity, ok := ArityOf(tc.args)
+		if !ok || arity != tc.expected {
+			t.Errorf("expected %+v, got %+v (%v)", tc.expected, arity, ok)
+		}
+	}
+
+	// Other validators are not known, even when they use those of cobra
+	called := false
+	custom := func(cmd *Command, args []string) error {
+		called = true
+		return ExactArgs(1)(cmd, args)
+	}
+	if _, ok := ArityOf(custom); ok {
+		t.Error("expected the arity of a custom validator to be unknown")
+	}
+	if _, ok := ArityOf(nil); ok {
+		t.Error("expected the arity of no validator to be unknown")
+	}
+	if called {
+		t.Error("expected the validator not to be called")
+	}
+}
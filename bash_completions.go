@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 	"sort"
 	"strings"
 
@@ -81,6 +80,8 @@ __%[1]s_handle_go_custom_completion()
     fi
 
     __%[1]s_debug "${FUNCNAME[0]}: calling ${requestComp}"
+    # Request the completions in format 3, where values are escaped
+    local -x COBRA_COMPLETION_FORMAT=3
     # Use eval to handle any environment variables and such
     out=$(eval "${requestComp}" 2>/dev/null)
 
@@ -100,6 +101,9 @@ __%[1]s_handle_go_custom_completion()
         __%[1]s_debug "${FUNCNAME[0]}: received error from custom completion go code"
         return
     else
+        if [ $((directive & %[6]d)) -ne 0 ]; then
+            __%[1]s_debug "${FUNCNAME[0]}: completion timed out, using the completions gathered so far"
+        fi
         if [ $((directive & %[4]d)) -ne 0 ]; then
             if [[ $(type -t compopt) = "builtin" ]]; then
                 __%[1]s_debug "${FUNCNAME[0]}: activating no space"
@@ -113,9 +117,46 @@ __%[1]s_handle_go_custom_completion()
             fi
         fi
 
-        while IFS='' read -r comp; do
-            COMPREPLY+=("$comp")
-        done < <(compgen -W "${out[*]}" -- "$cur")
+        if [ $((directive & %[7]d)) -ne 0 ]; then
+            # File extension filtering
+            local fullFilter filter filteringCmd
+            # Do not use quotes around the $out variable or else newline
+            # characters will be kept.
+            for filter in ${out[*]}; do
+                fullFilter+="$filter|"
+            done
+
+            filteringCmd="__%[1]s_handle_filename_extension_flag ${fullFilter%%|}"
+            __%[1]s_debug "File filtering command: $filteringCmd"
+            $filteringCmd
+        elif [ $((directive & %[8]d)) -ne 0 ]; then
+            # File completion for directories only
+            local subdir
+            # The directory is the value of the first completion
+            printf -v subdir "%%b" "${out%%%%$'\t'*}"
+            if [ -n "$subdir" ]; then
+                __%[1]s_debug "Listing directories in $subdir"
+                __%[1]s_handle_subdirs_in_dir_flag "$subdir"
+            else
+                __%[1]s_debug "Listing directories in ."
+                _filedir -d
+            fi
+        else
+            local value curValue
+            # Filter the values as the program received the current word,
+            # with its quotes and escapes removed
+            curValue=$(eval printf "%%s" "${cur}" 2>/dev/null) || curValue=${cur}
+            while IFS='' read -r comp; do
+                [ -z "${comp}" ] && continue
+                # The value precedes the first tab and is escaped by the program
+                printf -v value "%%b" "${comp%%%%$'\t'*}"
+                if [[ "${value}" == "${curValue}"* ]]; then
+                    # Quote the value so the shell reads it back as is
+                    printf -v comp "%%q" "${value}"
+                    COMPREPLY+=("${comp}")
+                fi
+            done <<< "${out}"
+        fi
     fi
 }
 
@@ -344,7 +385,8 @@ __%[1]s_handle_word()
     __%[1]s_handle_word
 }
 
-`, name, CompNoDescRequestCmd, BashCompDirectiveError, BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp))
+`, name, CompNoDescRequestCmd, BashCompDirectiveError, BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp, BashCompDirectiveTimeout,
+		BashCompDirectiveFilterFileExt, BashCompDirectiveFilterDirs))
 }
 
 func writePostscript(buf *bytes.Buffer, name string) {
@@ -472,6 +514,7 @@ func writeLocalNonPersistentFlag(buf *bytes.Buffer, flag *pflag.Flag) {
 
 // Setup annotations for go completions for registered flags
 func prepareCustomAnnotationsForFlags(cmd *Command) {
+	handler := []string{fmt.Sprintf("__%[1]s_handle_go_custom_completion", cmd.Root().Name())}
 	for flag := range flagCompletionFunctions {
 		// Make sure the completion script calls the __*_go_custom_completion function for
 		// every registered flag.  We need to do this here (and not when the flag was registered
@@ -480,8 +523,19 @@ func prepareCustomAnnotationsForFlags(cmd *Command) {
 		if flag.Annotations == nil {
 			flag.Annotations = map[string][]string{}
 		}
-		flag.Annotations[BashCompCustom] = []string{fmt.Sprintf("__%[1]s_handle_go_custom_completion", cmd.Root().Name())}
+		flag.Annotations[BashCompCustom] = handler
 	}
+	// The values of the enum flags are completed by the Go code as well
+	markEnumFlag := func(flag *pflag.Flag) {
+		if _, ok := flag.Annotations[FlagEnumValues]; !ok {
+			return
+		}
+		if _, ok := flag.Annotations[BashCompCustom]; !ok {
+			flag.Annotations[BashCompCustom] = handler
+		}
+	}
+	cmd.NonInheritedFlags().VisitAll(markEnumFlag)
+	cmd.InheritedFlags().VisitAll(markEnumFlag)
 }
 
 func writeFlags(buf *bytes.Buffer, cmd *Command) {
@@ -546,6 +600,15 @@ func writeRequiredFlag(buf *bytes.Buffer, cmd *Command) {
 
 func writeRequiredNouns(buf *bytes.Buffer, cmd *Command) {
 	buf.WriteString("    must_have_one_noun=()\n")
+	// The completions registered per position or delegated to another
+	// program are provided by the Go code
+	goArgCompletion := len(cmd.argCompletions) > 0 || cmd.CompletionDelegate != nil
+	if len(cmd.ValidArgs) > 0 && (cmd.VariadicValidArgs || goArgCompletion) {
+		// The Go code removes the arguments already given from the ValidArgs,
+		// and gives precedence to the other completions
+		buf.WriteString("    has_completion_function=1\n")
+		return
+	}
 	sort.Sort(sort.StringSlice(cmd.ValidArgs))
 	for _, value := range cmd.ValidArgs {
 		// Remove any description that may be included following a tab character.
@@ -553,7 +616,7 @@ func writeRequiredNouns(buf *bytes.Buffer, cmd *Command) {
 		value = strings.Split(value, "\t")[0]
 		buf.WriteString(fmt.Sprintf("    must_have_one_noun+=(%q)\n", value))
 	}
-	if cmd.ValidArgsFunction != nil {
+	if cmd.ValidArgsFunction != nil || goArgCompletion {
 		buf.WriteString("    has_completion_function=1\n")
 	}
 }
@@ -631,11 +694,12 @@ func nonCompletableFlag(flag *pflag.Flag) bool {
 
 // GenBashCompletionFile generates bash completion file.
 func (c *Command) GenBashCompletionFile(filename string) error {
-	outFile, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
+	return c.GenBashCompletionFS(DirFS(""), filename)
+}
 
-	return c.GenBashCompletion(outFile)
+// GenBashCompletionFS generates the bash completion file name in fs.
+func (c *Command) GenBashCompletionFS(fs WriteFS, name string) error {
+	return WriteFile(fs, name, func(w io.Writer) error {
+		return c.GenBashCompletion(w)
+	})
 }
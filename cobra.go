@@ -35,9 +35,12 @@ var templateFuncs = template.FuncMap{
 	"rpad":                    rpad,
 	"gt":                      Gt,
 	"eq":                      Eq,
+	"enumFlagUsages":          EnumFlagUsages,
 }
 
 var initializers []func()
+var runInitializers []func()
+var completionInitializers []func()
 
 // EnablePrefixMatching allows to set automatic prefix matching. Automatic prefix matching can be a dangerous thing
 // to automatically enable in CLI tools.
@@ -78,11 +81,25 @@ func AddTemplateFuncs(tmplFuncs template.FuncMap) {
 }
 
 // OnInitialize sets the passed functions to be run when each command's
-// Execute method is called.
+// Execute method is called.  When completions are requested, they run once
+// the flags of the command being completed are parsed.
 func OnInitialize(y ...func()) {
 	initializers = append(initializers, y...)
 }
 
+// OnRunInitialize sets the passed functions to be run when each command's
+// Execute method is called, except when completions are requested.
+func OnRunInitialize(y ...func()) {
+	runInitializers = append(runInitializers, y...)
+}
+
+// OnCompletionInitialize sets the passed functions to be run when completions
+// are requested, once the flags of the command being completed are parsed and
+// before its completion functions are called.
+func OnCompletionInitialize(y ...func()) {
+	completionInitializers = append(completionInitializers, y...)
+}
+
 // FIXME Gt is unused by cobra and should be removed in a version 2. It exists only for compatibility with users of cobra.
 
 // Gt takes two types and checks whether the first type is greater than the second. In case of types Arrays, Chans,
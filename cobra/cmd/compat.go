@@ -0,0 +1,84 @@
+// Copyright © 2020 Steve Francia <spf@spf13.com>.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var compatCmd = &cobra.Command{
+	Use:   "compat [old.json] [new.json]",
+	Short: "Report the breaking changes between two versions of a Cobra Application",
+	Long: `Compat (cobra compat) compares two versions of the command tree of
+a Cobra-based CLI application, as written by doc.GenJSON, and lists
+the removed or renamed commands, aliases and flags, and the other
+changes which may break the scripts using the old version.
+
+It exits with the status 1 if there is a breaking change, so that
+a release bumping only the minor or patch version can be checked.
+
+Example: cobra compat docs/v1.2.0.json docs/cli.json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The arguments are valid, the errors are not about the usage
+		cmd.SilenceUsage = true
+		report, err := compareJSONDocFiles(args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), report)
+		if report.HasBreakingChanges() {
+			return errBreakingChanges
+		}
+		return nil
+	},
+}
+
+// errBreakingChanges is returned by the compat command when the new version
+// has breaking changes, so that the cobra generator exits with the status 1.
+var errBreakingChanges = errors.New("the new version has breaking changes")
+
+// compareJSONDocFiles compares the command trees written by doc.GenJSON to
+// the files oldPath and newPath.
+func compareJSONDocFiles(oldPath, newPath string) (*doc.CompatReport, error) {
+	old, err := readJSONDocFile(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readJSONDocFile(newPath)
+	if err != nil {
+		return nil, err
+	}
+	return doc.CompareJSONDocs(old, current), nil
+}
+
+func readJSONDocFile(path string) (*doc.JSONDoc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	jsonDoc, err := doc.ReadJSONDoc(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return jsonDoc, nil
+}
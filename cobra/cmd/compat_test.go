@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareJSONDocFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-compat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath, newPath := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+	ioutil.WriteFile(oldPath, []byte(`{"version": 1, "command": {"name": "app", "path": "app", "runnable": true,
+		"options": [{"name": "count", "shorthand": "c", "type": "int", "default_value": "1"}]}}`), 0644)
+	ioutil.WriteFile(newPath, []byte(`{"version": 1, "command": {"name": "app", "path": "app", "runnable": true,
+		"options": [{"name": "count", "type": "int", "default_value": "1"}]}}`), 0644)
+
+	report, err := compareJSONDocFiles(oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := report.String(), "breaking: flag --count of \"app\" lost its shorthand -c\n"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	_, err = compareJSONDocFiles(oldPath, filepath.Join(dir, "missing.json"))
+	if err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("Expected an error about missing.json, got %v", err)
+	}
+}
+
+func TestCompatCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-compat-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPath, newPath := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+	ioutil.WriteFile(oldPath, []byte(`{"version": 1, "command": {"name": "app", "path": "app", "runnable": true,
+		"options": [{"name": "count", "shorthand": "c", "type": "int", "default_value": "1"}]}}`), 0644)
+	ioutil.WriteFile(newPath, []byte(`{"version": 1, "command": {"name": "app", "path": "app", "runnable": true,
+		"options": [{"name": "count", "type": "int", "default_value": "1"}]}}`), 0644)
+
+	out := new(bytes.Buffer)
+	compatCmd.SetOut(out)
+	defer compatCmd.SetOut(nil)
+	if err := compatCmd.RunE(compatCmd, []string{oldPath, newPath}); err != errBreakingChanges {
+		t.Errorf("Expected %v, got %v", errBreakingChanges, err)
+	}
+	if got, expected := out.String(), "breaking: flag --count of \"app\" lost its shorthand -c\n"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	out.Reset()
+	if err := compatCmd.RunE(compatCmd, []string{oldPath, oldPath}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no change, got %q", out.String())
+	}
+}
@@ -54,6 +54,7 @@ func init() {
 
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(compatCmd)
 }
 
 func initConfig() {
@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -61,6 +62,14 @@ type Command struct {
 	// It is a dynamic version of using ValidArgs.
 	// Only one of ValidArgs and ValidArgsFunction can be used for a command.
 	ValidArgsFunction func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective)
+	// VariadicValidArgs indicates that the command accepts several of the values provided
+	// by ValidArgs or ValidArgsFunction, each at most once.  Values already on the
+	// command-line are not offered again for completion.
+	VariadicValidArgs bool
+	// CompletionDelegate forwards the completion of the arguments, from a given
+	// position on, to another cobra-based program.  It is meant for commands
+	// wrapping another program, usually with DisableFlagParsing.
+	CompletionDelegate *CompletionDelegate
 
 	// Expected arguments
 	Args PositionalArgs
@@ -118,6 +127,11 @@ type Command struct {
 	// PersistentPostRunE: PersistentPostRun but returns an error.
 	PersistentPostRunE func(cmd *Command, args []string) error
 
+	// SkipPersistentPreRunOnCompletion prevents the PersistentPreRun (or
+	// PersistentPreRunE) hook of this command, as inherited by its children,
+	// from running before their completion functions are called.
+	SkipPersistentPreRunOnCompletion bool
+
 	// SilenceErrors is an option to quiet errors down stream.
 	SilenceErrors bool
 
@@ -149,6 +163,9 @@ type Command struct {
 	// FParseErrWhitelist flag parse errors to be ignored
 	FParseErrWhitelist FParseErrWhitelist
 
+	// CompletionOptions is a set of options to control the handling of shell completion
+	CompletionOptions CompletionOptions
+
 	ctx context.Context
 
 	// commands is the list of commands supported by this program.
@@ -208,11 +225,23 @@ type Command struct {
 	outWriter io.Writer
 	// errWriter is a writer defined by the user that replaces stderr
 	errWriter io.Writer
+
+	// completionCacheTTL is how long the results of ValidArgsFunction may be cached.
+	completionCacheTTL time.Duration
+	// completionRequest is set on the root command while completions are requested.
+	completionRequest bool
+	// argCompletions holds the completion functions registered per positional argument.
+	argCompletions map[int]func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective)
 }
 
 // Context returns underlying command context. If command wasn't
 // executed with ExecuteContext Context returns Background context.
+// While completion functions of the command run, it returns the context
+// of the completion request instead.
 func (c *Command) Context() context.Context {
+	if ctx := completionContextOf(c); ctx != nil {
+		return ctx
+	}
 	return c.ctx
 }
 
@@ -488,10 +517,10 @@ Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "he
   {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
 
 Flags:
-{{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
+{{enumFlagUsages .LocalFlags | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
 
 Global Flags:
-{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+{{enumFlagUsages .InheritedFlags | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
 
 Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
   {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
@@ -868,9 +897,16 @@ func (c *Command) execute(a []string) (err error) {
 }
 
 func (c *Command) preRun() {
+	if c.IsCompletionRequest() {
+		// Initializers run once the command being completed is known, see runCompletionHooks
+		return
+	}
 	for _, x := range initializers {
 		x()
 	}
+	for _, x := range runInitializers {
+		x()
+	}
 }
 
 // ExecuteContext is the same as Execute(), but sets the ctx on the command.
@@ -907,6 +943,8 @@ func (c *Command) ExecuteC() (cmd *Command, err error) {
 	// initialize help as the last point possible to allow for user
 	// overriding
 	c.InitDefaultHelpCmd()
+	// initialize completion at the last point to allow for user overriding
+	c.InitDefaultCompletionCmd()
 
 	args := c.args
 
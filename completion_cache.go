@@ -0,0 +1,168 @@
+package cobra
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// CompletionCacheDir is the directory under which cached completion results
+// are stored, in the "<root command name>/completion" subdirectory, which is
+// the only one the cache writes to or removes. If empty, the user cache
+// directory (see os.UserCacheDir) is used.
+var CompletionCacheDir = ""
+
+// Global map of the time-to-live of cached flag completion results.
+var flagCompletionCacheTTLs = map[*pflag.Flag]time.Duration{}
+
+// completionCacheEntry is the on-disk representation of cached completion results.
+type completionCacheEntry struct {
+	Created     time.Time         `json:"created"`
+	Completions []string          `json:"completions"`
+	Directive   BashCompDirective `json:"directive"`
+}
+
+// SetCompletionCacheTTL enables caching of the results of the ValidArgsFunction
+// of this command. Cached results are reused for the same command path, flag
+// values, arguments and word to complete until ttl has elapsed.
+// A ttl of zero or less disables caching.
+func (c *Command) SetCompletionCacheTTL(ttl time.Duration) {
+	c.completionCacheTTL = ttl
+}
+
+// SetFlagCompletionCacheTTL enables caching of the results of the completion
+// function registered for the named flag. Cached results are reused for the
+// same command path, flag values, arguments and word to complete until ttl
+// has elapsed.
+// A ttl of zero or less disables caching.
+func (c *Command) SetFlagCompletionCacheTTL(flagName string, ttl time.Duration) error {
+	flag := c.Flag(flagName)
+	if flag == nil {
+		return fmt.Errorf("SetFlagCompletionCacheTTL: flag '%s' does not exist", flagName)
+	}
+	if ttl <= 0 {
+		delete(flagCompletionCacheTTLs, flag)
+		return nil
+	}
+	flagCompletionCacheTTLs[flag] = ttl
+	return nil
+}
+
+// InvalidateCompletionCache removes the cached completion results of this command.
+func (c *Command) InvalidateCompletionCache() error {
+	dir, err := c.completionCacheDir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(dir, completionCacheHash(c.CommandPath())))
+}
+
+// ClearCompletionCache removes the cached completion results of every command
+// of the program. Only the subdirectory of the cache of the program is
+// removed, not the other files of CompletionCacheDir.
+func (c *Command) ClearCompletionCache() error {
+	dir, err := c.completionCacheDir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (c *Command) completionCacheDir() (string, error) {
+	dir := CompletionCacheDir
+	if dir == "" {
+		var err error
+		if dir, err = os.UserCacheDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, c.Root().Name(), "completion"), nil
+}
+
+// completionCachePath returns the file where the completion results for the
+// specified request are cached.  The values of the flags set on the command
+// line are part of the request, as the completion function may use them.
+func (c *Command) completionCachePath(flag *pflag.Flag, args []string, toComplete string) (string, error) {
+	dir, err := c.completionCacheDir()
+	if err != nil {
+		return "", err
+	}
+	key := []string{c.CommandPath()}
+	if flag != nil {
+		key = append(key, "--"+flag.Name)
+	}
+	var flagValues []string
+	c.Flags().Visit(func(f *pflag.Flag) {
+		flagValues = append(flagValues, "--"+f.Name+"="+f.Value.String())
+	})
+	sort.Strings(flagValues)
+	key = append(key, flagValues...)
+	key = append(key, args...)
+	key = append(key, toComplete)
+	return filepath.Join(dir, completionCacheHash(c.CommandPath()), completionCacheHash(key...)+".json"), nil
+}
+
+func completionCacheHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// completionCacheTTLFor returns how long the results of the completion function
+// for the flag (or for the arguments of the command if flag is nil) may be cached.
+func (c *Command) completionCacheTTLFor(flag *pflag.Flag) time.Duration {
+	if flag != nil {
+		return flagCompletionCacheTTLs[flag]
+	}
+	return c.completionCacheTTL
+}
+
+// callCompletionFunc calls completionFn, or reuses its cached results if
+// caching is enabled and the cached results have not expired.
+func (c *Command) callCompletionFunc(completionFn func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective),
+	flag *pflag.Flag, args []string, toComplete string) ([]string, BashCompDirective) {
+	ttl := c.completionCacheTTLFor(flag)
+	if ttl <= 0 {
+		return completionFn(c, args, toComplete)
+	}
+
+	path, err := c.completionCachePath(flag, args, toComplete)
+	if err != nil {
+		CompDebugln("Unable to determine the completion cache location: "+err.Error(), false)
+		return completionFn(c, args, toComplete)
+	}
+
+	if data, err := ioutil.ReadFile(path); err == nil {
+		var entry completionCacheEntry
+		if err := json.Unmarshal(data, &entry); err == nil && time.Since(entry.Created) < ttl {
+			CompDebugln("Using cached completions from "+path, false)
+			return entry.Completions, entry.Directive
+		}
+	}
+
+	comps, directive := completionFn(c, args, toComplete)
+	if directive&BashCompDirectiveError != 0 {
+		// Never cache errors so that the next request tries again
+		return comps, directive
+	}
+
+	data, err := json.Marshal(completionCacheEntry{Created: time.Now(), Completions: comps, Directive: directive})
+	if err == nil {
+		// The completions may be sensitive, such as the names of resources
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+			err = ioutil.WriteFile(path, data, 0600)
+		}
+	}
+	if err != nil {
+		CompDebugln("Unable to cache completions: "+err.Error(), false)
+	}
+	return comps, directive
+}
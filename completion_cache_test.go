@@ -0,0 +1,170 @@
+package cobra
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompletionCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-comp-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CompletionCacheDir = dir
+	defer func() { CompletionCacheDir = "" }()
+
+	calls := 0
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use: "child",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			calls++
+			return []string{"one\tThe first", "two\tThe second"}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	childCmd.SetCompletionCacheTTL(time.Hour)
+	rootCmd.AddCommand(childCmd)
+
+	expected := strings.Join([]string{
+		"one\tThe first",
+		"two\tThe second",
+		":4",
+		"Completion ended with directive: BashCompDirectiveNoFileComp", ""}, "\n")
+
+	for i := 0; i < 2; i++ {
+		output, err := executeCommand(rootCmd, CompRequestCmd, "child", "")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if output != expected {
+			t.Errorf("expected: %q, got: %q", expected, output)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the completion function to be called once, got %d calls", calls)
+	}
+
+	// A different word to complete is a different cache entry
+	if _, err := executeCommand(rootCmd, CompRequestCmd, "child", "t"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the completion function to be called twice, got %d calls", calls)
+	}
+
+	if err := childCmd.InvalidateCompletionCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand(rootCmd, CompRequestCmd, "child", ""); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected the completion function to be called after invalidation, got %d calls", calls)
+	}
+
+	entries, err := filepath.Glob(filepath.Join(dir, "root", "completion", "*", "*.json"))
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("expected cache entries under the directory of the program, got %v, %v", entries, err)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(entries[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("expected the cache entries to be private, got mode %v", perm)
+		}
+	}
+
+	// The other files of the cache directory are kept
+	other := filepath.Join(dir, "other")
+	if err := ioutil.WriteFile(other, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootCmd.ClearCompletionCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "root", "completion")); !os.IsNotExist(err) {
+		t.Errorf("expected the cache of the program to be removed, got %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("expected the other files of the cache directory to be kept, got %v", err)
+	}
+}
+
+func TestFlagCompletionCacheIgnoresErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-comp-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CompletionCacheDir = dir
+	defer func() { CompletionCacheDir = "" }()
+
+	calls := 0
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().String("output", "", "output format")
+	rootCmd.RegisterFlagCompletionFunc("output", func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		calls++
+		return nil, BashCompDirectiveError
+	})
+	if err := rootCmd.SetFlagCompletionCacheTTL("output", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootCmd.SetFlagCompletionCacheTTL("unknown", time.Hour); err == nil {
+		t.Error("expected an error for an unknown flag")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := executeCommand(rootCmd, CompRequestCmd, "--output", ""); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected error results not to be cached, got %d calls", calls)
+	}
+}
+
+func TestCompletionCacheFlagValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-comp-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CompletionCacheDir = dir
+	defer func() { CompletionCacheDir = "" }()
+
+	calls := 0
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.PersistentFlags().String("namespace", "", "namespace")
+	childCmd := &Command{
+		Use: "child",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			calls++
+			namespace, _ := cmd.Flags().GetString("namespace")
+			return []string{"pod-of-" + namespace}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	childCmd.SetCompletionCacheTTL(time.Hour)
+	rootCmd.AddCommand(childCmd)
+
+	// The output is the same whether or not the cache is hit
+	for _, namespace := range []string{"a", "b", "a"} {
+		output, err := executeCommand(rootCmd, CompRequestCmd, "child", "--namespace", namespace, "")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		checkStringContains(t, output, "pod-of-"+namespace+"\n")
+	}
+	if calls != 2 {
+		t.Errorf("expected the completion function to be called once per namespace, got %d calls", calls)
+	}
+}
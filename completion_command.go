@@ -0,0 +1,238 @@
+package cobra
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const compCmdName = "completion"
+
+// CompletionOptions are the options to control shell completion
+type CompletionOptions struct {
+	// DisableDefaultCmd prevents Cobra from creating a default 'completion' command
+	DisableDefaultCmd bool
+	// DisableNoDescFlag prevents Cobra from creating the '--no-descriptions' flag
+	// for shells that support completion descriptions
+	DisableNoDescFlag bool
+	// DisableDescriptions turns off all completion descriptions for shells
+	// that support them
+	DisableDescriptions bool
+	// HiddenDefaultCmd makes the default 'completion' command hidden
+	HiddenDefaultCmd bool
+	// Timeout is the maximum amount of time completion functions may run
+	// before the completions gathered so far are returned with
+	// BashCompDirectiveTimeout.  No timeout is applied if zero.
+	Timeout time.Duration
+}
+
+// completionScriptLocation returns the file in which the completion script
+// of the program named name must be installed for the specified shell to
+// load it automatically for the current user.
+func completionScriptLocation(shell, name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	switch shell {
+	case "bash":
+		return filepath.Join(dataHome, "bash-completion", "completions", name), nil
+	case "zsh":
+		return filepath.Join(dataHome, "zsh", "site-functions", "_"+name), nil
+	case "fish":
+		return filepath.Join(configHome, "fish", "completions", name+".fish"), nil
+	}
+	return "", fmt.Errorf("installing the completion script is not supported for %s", shell)
+}
+
+// InitDefaultCompletionCmd adds a default 'completion' command to c.
+// This function will do nothing if any of the following is true:
+// 1- the feature has been explicitly disabled by the program,
+// 2- c has no subcommands (to avoid creating one),
+// 3- c already has a 'completion' command provided by the program.
+func (c *Command) InitDefaultCompletionCmd() {
+	if c.CompletionOptions.DisableDefaultCmd || !c.HasSubCommands() {
+		return
+	}
+
+	for _, cmd := range c.commands {
+		if cmd.Name() == compCmdName || cmd.HasAlias(compCmdName) {
+			// A completion command is already available
+			return
+		}
+	}
+
+	haveNoDescFlag := !c.CompletionOptions.DisableNoDescFlag && !c.CompletionOptions.DisableDescriptions
+	name := c.Name()
+
+	completionCmd := &Command{
+		Use:   compCmdName,
+		Short: "Generate the autocompletion script for the specified shell",
+		Long: fmt.Sprintf(`Generate the autocompletion script for %[1]s for the specified shell.
+See each sub-command's help for details on how to use the generated script.
+
+Use the --install flag of a sub-command to write the script to the location
+from which that shell loads completions for the current user.
+`, name),
+		Args:   NoArgs,
+		Hidden: c.CompletionOptions.HiddenDefaultCmd,
+	}
+	c.AddCommand(completionCmd)
+
+	var noDesc, install bool
+
+	// genAndInstall calls gen to write the completion script for shell to
+	// stdout, or, if the --install flag was given, to the user completion
+	// directory of that shell.
+	genAndInstall := func(cmd *Command, shell string, gen func(buf *bytes.Buffer) error) error {
+		buf := new(bytes.Buffer)
+		if err := gen(buf); err != nil {
+			return err
+		}
+		if !install {
+			_, err := buf.WriteTo(cmd.OutOrStdout())
+			return err
+		}
+
+		location, err := completionScriptLocation(shell, name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(location), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(location, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Completion script for %s installed in %s\n", shell, location)
+		return nil
+	}
+
+	bash := &Command{
+		Use:   "bash",
+		Short: "Generate the autocompletion script for bash",
+		Long: fmt.Sprintf(`Generate the autocompletion script for the bash shell.
+
+This script depends on the 'bash-completion' package.
+If it is not installed already, you can install it via your OS's package manager.
+
+To load completions in your current shell session:
+
+	source <(%[1]s completion bash)
+
+To load completions for every new session, execute once:
+
+	%[1]s completion bash --install
+
+which writes the script to $XDG_DATA_HOME/bash-completion/completions/%[1]s.
+
+You will need to start a new shell for this setup to take effect.
+`, name),
+		Args: NoArgs,
+		RunE: func(cmd *Command, args []string) error {
+			return genAndInstall(cmd, "bash", func(buf *bytes.Buffer) error {
+				return cmd.Root().GenBashCompletion(buf)
+			})
+		},
+	}
+
+	zsh := &Command{
+		Use:   "zsh",
+		Short: "Generate the autocompletion script for zsh",
+		Long: fmt.Sprintf(`Generate the autocompletion script for the zsh shell.
+
+If shell completion is not already enabled in your environment you will need
+to enable it.  You can execute the following once:
+
+	echo "autoload -U compinit; compinit" >> ~/.zshrc
+
+To load completions in your current shell session:
+
+	source <(%[1]s completion zsh)
+
+To load completions for every new session, execute once:
+
+	%[1]s completion zsh --install
+
+which writes the script to $XDG_DATA_HOME/zsh/site-functions/_%[1]s.
+That directory must be part of your fpath; add the following to ~/.zshrc
+before compinit is called if it is not:
+
+	fpath=(${XDG_DATA_HOME:-$HOME/.local/share}/zsh/site-functions $fpath)
+
+You will need to start a new shell for this setup to take effect.
+`, name),
+		Args: NoArgs,
+		RunE: func(cmd *Command, args []string) error {
+			return genAndInstall(cmd, "zsh", func(buf *bytes.Buffer) error {
+				return cmd.Root().GenZshCompletionV2(buf, !noDesc && !c.CompletionOptions.DisableDescriptions)
+			})
+		},
+	}
+
+	fish := &Command{
+		Use:   "fish",
+		Short: "Generate the autocompletion script for fish",
+		Long: fmt.Sprintf(`Generate the autocompletion script for the fish shell.
+
+To load completions in your current shell session:
+
+	%[1]s completion fish | source
+
+To load completions for every new session, execute once:
+
+	%[1]s completion fish --install
+
+which writes the script to $XDG_CONFIG_HOME/fish/completions/%[1]s.fish.
+
+You will need to start a new shell for this setup to take effect.
+`, name),
+		Args: NoArgs,
+		RunE: func(cmd *Command, args []string) error {
+			return genAndInstall(cmd, "fish", func(buf *bytes.Buffer) error {
+				return cmd.Root().GenFishCompletion(buf, !noDesc && !c.CompletionOptions.DisableDescriptions)
+			})
+		},
+	}
+
+	powershell := &Command{
+		Use:   "powershell",
+		Short: "Generate the autocompletion script for powershell",
+		Long: fmt.Sprintf(`Generate the autocompletion script for powershell.
+
+To load completions in your current shell session:
+
+	%[1]s completion powershell | Out-String | Invoke-Expression
+
+To load completions for every new session, add the output of the above command
+to your powershell profile.
+`, name),
+		Args:                  NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *Command, args []string) error {
+			return cmd.Root().GenPowerShellCompletion(cmd.OutOrStdout())
+		},
+	}
+
+	for _, shellCmd := range []*Command{bash, zsh, fish} {
+		shellCmd.Flags().BoolVar(&install, "install", false, "install the completion script for the current user")
+	}
+	if haveNoDescFlag {
+		zsh.Flags().BoolVar(&noDesc, "no-descriptions", false, "disable completion descriptions")
+		fish.Flags().BoolVar(&noDesc, "no-descriptions", false, "disable completion descriptions")
+	}
+
+	completionCmd.AddCommand(bash, zsh, fish, powershell)
+}
@@ -0,0 +1,131 @@
+package cobra
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultCompletionCmd(t *testing.T) {
+	rootCmd := &Command{Use: "root", Args: NoArgs, Run: emptyRun}
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+
+	output, err := executeCommand(rootCmd, "completion", "bash")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "# bash completion for root")
+
+	output, err = executeCommand(rootCmd, "completion", "zsh")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "#compdef __root root")
+	checkStringContains(t, output, CompRequestCmd+" ")
+
+	output, err = executeCommand(rootCmd, "completion", "fish", "--no-descriptions")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "# fish completion for root")
+	checkStringContains(t, output, CompNoDescRequestCmd)
+
+	output, err = executeCommand(rootCmd, "completion", "powershell")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "Register-ArgumentCompleter -Native -CommandName 'root'")
+
+	output, err = executeCommand(rootCmd, "completion", "bash", "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "source <(root completion bash)")
+}
+
+func TestDefaultCompletionCmdOptions(t *testing.T) {
+	rootCmd := &Command{Use: "root", Args: NoArgs, Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "child", Run: emptyRun})
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	if _, err := executeCommand(rootCmd, "completion", "bash"); err == nil {
+		t.Error("expected an error when the default completion command is disabled")
+	}
+
+	rootCmd = &Command{Use: "root", Args: NoArgs, Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "child", Run: emptyRun})
+	rootCmd.CompletionOptions.DisableNoDescFlag = true
+
+	if _, err := executeCommand(rootCmd, "completion", "zsh", "--no-descriptions"); err == nil {
+		t.Error("expected an error when the --no-descriptions flag is disabled")
+	}
+
+	// A program-provided completion command is not replaced
+	rootCmd = &Command{Use: "root", Args: NoArgs, Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "completion", Run: func(*Command, []string) { rootCmd.Print("custom") }})
+	output, err := executeCommand(rootCmd, "completion")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if output != "custom" {
+		t.Errorf("expected the program's completion command to run, got %q", output)
+	}
+
+	// No completion command is added to a program without subcommands
+	rootCmd = &Command{Use: "root", Run: emptyRun}
+	rootCmd.InitDefaultCompletionCmd()
+	if rootCmd.HasSubCommands() {
+		t.Error("expected no completion command for a program without subcommands")
+	}
+}
+
+func TestDefaultCompletionCmdInstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-comp-install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, env := range []string{"XDG_DATA_HOME", "XDG_CONFIG_HOME"} {
+		old, set := os.LookupEnv(env)
+		os.Setenv(env, filepath.Join(dir, env))
+		defer func(env string) {
+			if set {
+				os.Setenv(env, old)
+			} else {
+				os.Unsetenv(env)
+			}
+		}(env)
+	}
+
+	rootCmd := &Command{Use: "root", Args: NoArgs, Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "child", Run: emptyRun})
+
+	tests := []struct {
+		shell    string
+		location string
+		header   string
+	}{
+		{"bash", filepath.Join(dir, "XDG_DATA_HOME", "bash-completion", "completions", "root"), "# bash completion for root"},
+		{"zsh", filepath.Join(dir, "XDG_DATA_HOME", "zsh", "site-functions", "_root"), "#compdef __root root"},
+		{"fish", filepath.Join(dir, "XDG_CONFIG_HOME", "fish", "completions", "root.fish"), "# fish completion for root"},
+	}
+	for _, tc := range tests {
+		output, err := executeCommand(rootCmd, "completion", tc.shell, "--install")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		checkStringContains(t, output, tc.location)
+
+		script, err := ioutil.ReadFile(tc.location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(script), tc.header) {
+			t.Errorf("expected %s to contain the %s completion script", tc.location, tc.shell)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cobra
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CompletionDelegate describes how a command forwards the completion of its
+// arguments to the __complete command of another cobra-based program.
+type CompletionDelegate struct {
+	// ArgPosition is the position of the first argument (first argument is 1)
+	// forwarded to the other program.  For a command with DisableFlagParsing,
+	// flags and "--" count as arguments.
+	ArgPosition int
+	// Program is the name or path of the program completion is delegated to.
+	// If empty, the argument at ArgPosition is the program and the arguments
+	// following it are forwarded (e.g., "app exec kubectl get <TAB>"), provided
+	// it is one of AllowedPrograms.
+	Program string
+	// AllowedPrograms are the names or paths of the programs that can be named
+	// by the argument at ArgPosition when Program is empty.  Completion runs the
+	// __complete command of the program on <TAB>, and a program that is not
+	// cobra-based could instead perform its own action with these arguments:
+	// only list programs known to be cobra-based.  Other programs are not run.
+	AllowedPrograms []string
+	// Fallback is the name or path of a program to delegate to instead when
+	// Program cannot be run or does not provide any completion.
+	Fallback string
+}
+
+// completionDelegateFunc returns the completion function forwarding the
+// completion of the argument at argPosition to the program of the
+// CompletionDelegate of the command, or nil if it is not forwarded.
+func (c *Command) completionDelegateFunc(argPosition int) func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+	d := c.CompletionDelegate
+	if d == nil || d.ArgPosition < 1 || argPosition < d.ArgPosition {
+		return nil
+	}
+	if d.Program == "" && argPosition == d.ArgPosition {
+		// The program itself is being completed
+		return nil
+	}
+
+	return func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		program, forwarded := d.Program, args[d.ArgPosition-1:]
+		if program == "" {
+			program, forwarded = forwarded[0], forwarded[1:]
+			if !stringInSlice(program, d.AllowedPrograms) {
+				CompDebugln(fmt.Sprintf("Not delegating completion to %s, which is not an allowed program", program), false)
+				return nil, BashCompDirectiveDefault
+			}
+		}
+
+		comps, directive, err := cmd.delegateCompletion(program, forwarded, toComplete)
+		if (err != nil || len(comps) == 0) && d.Fallback != "" {
+			if err != nil {
+				CompDebugln(fmt.Sprintf("Delegating completion to %s failed: %v", program, err), false)
+			} else {
+				CompDebugln(fmt.Sprintf("%s did not provide any completion", program), false)
+			}
+			fallbackComps, fallbackDirective, fallbackErr := cmd.delegateCompletion(d.Fallback, forwarded, toComplete)
+			// Keep the result of the program if the fallback cannot be run either
+			if fallbackErr == nil || err != nil {
+				comps, directive, err = fallbackComps, fallbackDirective, fallbackErr
+			}
+		}
+		if err != nil {
+			// Let the shell perform its default completion
+			CompDebugln(fmt.Sprintf("Unable to delegate completion: %v", err), false)
+			return nil, BashCompDirectiveDefault
+		}
+		return comps, directive
+	}
+}
+
+// delegateCompletion runs the __complete command of program to complete
+// toComplete following args, and returns the completions and directive it printed.
+func (c *Command) delegateCompletion(program string, args []string, toComplete string) ([]string, BashCompDirective, error) {
+	request := append([]string{CompRequestCmd}, args...)
+	request = append(request, toComplete)
+
+	ctx := c.Context()
+	if ctx == nil {
+		return nil, BashCompDirectiveDefault, fmt.Errorf("no context to run %s", program)
+	}
+	delegateCmd := exec.CommandContext(ctx, program, request...)
+	delegateCmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", CompFormatEnvVar, CompFormatVersion))
+	out, err := delegateCmd.Output()
+	if err != nil {
+		return nil, BashCompDirectiveDefault, err
+	}
+	return parseDelegateOutput(program, out)
+}
+
+// parseDelegateOutput parses the output of the __complete command of another
+// program, requested in the latest format.  Programs built with an older version
+// of cobra print the legacy format, which is not escaped: the output is only
+// unescaped if one of its completions has the four fields of the latest format.
+func parseDelegateOutput(program string, out []byte) ([]string, BashCompDirective, error) {
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, BashCompDirectiveDefault, fmt.Errorf("%s did not print a completion directive", program)
+	}
+	directive, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, BashCompDirectiveDefault, fmt.Errorf("%s printed an invalid completion directive: %q", program, last)
+	}
+
+	escaped := false
+	for _, line := range lines[:len(lines)-1] {
+		if strings.Count(line, "\t") >= 3 {
+			escaped = true
+			break
+		}
+	}
+
+	var comps []string
+	for _, line := range lines[:len(lines)-1] {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 4)
+		var comp Completion
+		for i, field := range fields {
+			if escaped {
+				field = unescapeCompField(field)
+			}
+			switch i {
+			case 0:
+				comp.Value = field
+			case 1:
+				comp.Description = field
+			case 2:
+				comp.Group = field
+			case 3:
+				comp.Kind = CompletionKind(field)
+			}
+		}
+		comps = append(comps, comp.String())
+	}
+
+	// Only keep the directives known to this program
+	return comps, BashCompDirective(directive) & (bashCompDirectiveMaxValue - 1), nil
+}
@@ -0,0 +1,168 @@
+package cobra
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeFakeProgram writes a shell script printing the arguments it received
+// as a completion, followed by output, and returns its path.
+func writeFakeProgram(t *testing.T, dir, name, output string) string {
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\nprintf 'args:%s\\t\\t\\t\\n' \"$*\"\nprintf '" + output + "'\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompletionDelegate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake programs are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "cobra-delegate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kubectl := writeFakeProgram(t, dir, "kubectl", `pods\tThe pods\tresources\t\n:6\n`)
+	broken := writeFakeProgram(t, dir, "broken", `no directive\n`)
+	// A program without completions, not even its arguments
+	silent := filepath.Join(dir, "silent")
+	if err := ioutil.WriteFile(silent, []byte("#!/bin/sh\nprintf ':4\\n'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	execCmd := &Command{
+		Use:                "exec",
+		DisableFlagParsing: true,
+		CompletionDelegate: &CompletionDelegate{ArgPosition: 2, AllowedPrograms: []string{kubectl}},
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			return []string{"--"}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	kCmd := &Command{
+		Use:                "k",
+		Args:               ArbitraryArgs,
+		CompletionDelegate: &CompletionDelegate{ArgPosition: 1, Program: "does-not-exist", Fallback: kubectl},
+		Run:                emptyRun,
+	}
+	brokenCmd := &Command{
+		Use:                "broken",
+		Args:               ArbitraryArgs,
+		CompletionDelegate: &CompletionDelegate{ArgPosition: 1, Program: broken},
+		Run:                emptyRun,
+	}
+	silentCmd := &Command{
+		Use:                "silent",
+		Args:               ArbitraryArgs,
+		CompletionDelegate: &CompletionDelegate{ArgPosition: 1, Program: silent, Fallback: kubectl},
+		Run:                emptyRun,
+	}
+	silentBrokenCmd := &Command{
+		Use:                "silent-broken",
+		Args:               ArbitraryArgs,
+		CompletionDelegate: &CompletionDelegate{ArgPosition: 1, Program: silent, Fallback: broken},
+		Run:                emptyRun,
+	}
+	rootCmd.AddCommand(execCmd, kCmd, brokenCmd, silentCmd, silentBrokenCmd)
+
+	testcases := []struct {
+		args     []string
+		expected []string
+	}{
+		// Before the delegated position, the command completes itself
+		{[]string{"exec", ""}, []string{
+			"--",
+			":4",
+			"Completion ended with directive: BashCompDirectiveNoFileComp", ""}},
+		// The program to delegate to is being completed
+		{[]string{"exec", "--", "kub"}, []string{
+			"--",
+			":4",
+			"Completion ended with directive: BashCompDirectiveNoFileComp", ""}},
+		// Flags are forwarded as well
+		{[]string{"exec", "--", kubectl, "get", "--namespace", "ns", "po"}, []string{
+			"args:__complete get --namespace ns po",
+			"pods\tThe pods",
+			":6",
+			"Completion ended with directive: BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp", ""}},
+		// Programs that are not allowed are not run
+		{[]string{"exec", "--", silent, ""}, []string{
+			":0",
+			"Completion ended with directive: BashCompDirectiveDefault", ""}},
+		// The fallback program is used when the program cannot be run
+		{[]string{"k", "get", ""}, []string{
+			"args:__complete get ",
+			"pods\tThe pods",
+			":6",
+			"Completion ended with directive: BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp", ""}},
+		// The fallback program is used when the program does not provide any completion
+		{[]string{"silent", "get", ""}, []string{
+			"args:__complete get ",
+			"pods\tThe pods",
+			":6",
+			"Completion ended with directive: BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp", ""}},
+		// unless the fallback program cannot be run either
+		{[]string{"silent-broken", ""}, []string{
+			":4",
+			"Completion ended with directive: BashCompDirectiveNoFileComp", ""}},
+		// Without completions from the program, the shell performs its default completion
+		{[]string{"broken", ""}, []string{
+			":0",
+			"Completion ended with directive: BashCompDirectiveDefault", ""}},
+	}
+	for _, tc := range testcases {
+		output, err := executeCommand(rootCmd, append([]string{CompRequestCmd}, tc.args...)...)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		expected := strings.Join(tc.expected, "\n")
+		if output != expected {
+			t.Errorf("%v: expected: %q, got: %q", tc.args, expected, output)
+		}
+	}
+
+	// Bash requests the completions of the delegating commands from the Go code
+	buf := new(bytes.Buffer)
+	if err := rootCmd.GenBashCompletion(buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "_root_k()\n")
+	if n := strings.Count(buf.String(), "    has_completion_function=1\n"); n != 5 {
+		t.Errorf("expected the 5 delegating commands to have a completion function, got %d", n)
+	}
+}
+
+func TestParseDelegateOutput(t *testing.T) {
+	testcases := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		// The legacy format is not escaped
+		{"legacy", "a\\tb\tdesc\\n\nc\n:4\n", []string{"a\\tb\tdesc\\n", "c"}},
+		// Every field of the latest format is unescaped, even on lines with fewer fields
+		{"escaped", "a\\tb\tdesc\\n\t\tvalue\nback\\\\slash\n:4\n", []string{"a\tb\tdesc\n\t\tvalue", "back\\slash"}},
+	}
+	for _, tc := range testcases {
+		comps, directive, err := parseDelegateOutput("prog", []byte(tc.output))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if directive != BashCompDirectiveNoFileComp {
+			t.Errorf("%s: expected directive %d, got %d", tc.name, BashCompDirectiveNoFileComp, directive)
+		}
+		if strings.Join(comps, "|") != strings.Join(tc.expected, "|") {
+			t.Errorf("%s: expected: %q, got: %q", tc.name, tc.expected, comps)
+		}
+	}
+}
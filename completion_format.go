@@ -0,0 +1,134 @@
+package cobra
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CompFormatEnvVar is the environment variable the completion scripts use to
+	// request a version of the __complete output format.  Without it, or if it
+	// holds "1", the legacy format is used: one "value<TAB>description" per line.
+	CompFormatEnvVar = "COBRA_COMPLETION_FORMAT"
+	// CompFormatVersion is the latest version of the __complete output format.
+	// Version 2 prints every completion as "value<TAB>description<TAB>group<TAB>kind".
+	// Version 3 additionally escapes each field: a backslash is printed as "\\",
+	// a tab as "\t" and a newline as "\n".
+	CompFormatVersion = 3
+)
+
+// compFieldEscaper escapes the fields of the __complete output from version 3 on.
+var compFieldEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n")
+
+// CompletionKind tells what a completion choice designates.
+type CompletionKind string
+
+const (
+	// CompletionKindValue is a value for an argument or a flag.
+	CompletionKindValue CompletionKind = "value"
+	// CompletionKindCommand is the name of a subcommand.
+	CompletionKindCommand CompletionKind = "command"
+	// CompletionKindFlag is the name of a flag.
+	CompletionKindFlag CompletionKind = "flag"
+	// CompletionKindFile is the name of a file.
+	CompletionKindFile CompletionKind = "file"
+	// CompletionKindDirectory is the name of a directory.
+	CompletionKindDirectory CompletionKind = "directory"
+)
+
+const (
+	// compGroupCommands is the group of the subcommand names completed by cobra.
+	compGroupCommands = "commands"
+	// compGroupFlags is the group of the flag names completed by cobra.
+	compGroupFlags = "flags"
+)
+
+// String returns the completion in the form returned by completion functions,
+// which is "value<TAB>description<TAB>group<TAB>kind" with trailing empty fields
+// omitted.  A completion function can therefore return structured choices:
+//
+//	return []string{cobra.Completion{Value: "prod", Group: "clusters"}.String()}, cobra.BashCompDirectiveNoFileComp
+func (c Completion) String() string {
+	fields := []string{c.Value, c.Description, c.Group, string(c.Kind)}
+	for len(fields) > 1 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, "\t")
+}
+
+// parseCompletion parses a completion returned by a completion function,
+// which can be a plain value, a "value<TAB>description" or the structured
+// form returned by Completion.String().
+func parseCompletion(comp string) Completion {
+	var completion Completion
+	fields := strings.SplitN(comp, "\t", 4)
+	completion.Value = fields[0]
+	if len(fields) > 1 {
+		completion.Description = fields[1]
+	}
+	if len(fields) > 2 {
+		completion.Group = fields[2]
+	}
+	if len(fields) > 3 {
+		completion.Kind = CompletionKind(fields[3])
+	}
+	return completion
+}
+
+// unescapeCompField reverts the escaping of a field of the __complete output
+// in version 3 or later.
+func unescapeCompField(field string) string {
+	var result strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+1 < len(field) {
+			i++
+			switch field[i] {
+			case 't':
+				result.WriteByte('\t')
+			case 'n':
+				result.WriteByte('\n')
+			default:
+				result.WriteByte(field[i])
+			}
+			continue
+		}
+		result.WriteByte(field[i])
+	}
+	return result.String()
+}
+
+// requestedCompFormat returns the version of the __complete output format
+// requested by the completion script.
+func requestedCompFormat() int {
+	version, err := strconv.Atoi(os.Getenv(CompFormatEnvVar))
+	if err != nil || version < 1 {
+		return 1
+	}
+	if version > CompFormatVersion {
+		return CompFormatVersion
+	}
+	return version
+}
+
+// formatCompletion formats a completion for the __complete output in the given version.
+func formatCompletion(comp Completion, version int, includeDesc bool) string {
+	if !includeDesc {
+		comp.Description = ""
+	}
+	if version < 2 {
+		if comp.Description == "" {
+			return comp.Value
+		}
+		return fmt.Sprintf("%s\t%s", comp.Value, comp.Description)
+	}
+	if version < 3 {
+		return fmt.Sprintf("%s\t%s\t%s\t%s", comp.Value, comp.Description, comp.Group, comp.Kind)
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s",
+		compFieldEscaper.Replace(comp.Value),
+		compFieldEscaper.Replace(comp.Description),
+		compFieldEscaper.Replace(comp.Group),
+		compFieldEscaper.Replace(string(comp.Kind)))
+}
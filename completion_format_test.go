@@ -0,0 +1,102 @@
+package cobra
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStructuredCompletions(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use:   "child",
+		Short: "The child",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			return []string{
+				Completion{Value: "prod", Description: "Production", Group: "clusters"}.String(),
+				Completion{Value: "main.go", Kind: CompletionKindFile}.String(),
+				"plain\tA plain value",
+			}, BashCompDirectiveDefault
+		},
+		Run: emptyRun,
+	}
+	childCmd.Flags().Bool("verbose", false, "be verbose")
+	rootCmd.AddCommand(childCmd)
+
+	// The legacy format only carries the value and the description
+	output, err := executeCommand(rootCmd, CompRequestCmd, "child", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"prod\tProduction",
+		"main.go",
+		"plain\tA plain value",
+		":0",
+		"Completion ended with directive: BashCompDirectiveDefault", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	os.Setenv(CompFormatEnvVar, "2")
+	defer os.Unsetenv(CompFormatEnvVar)
+
+	output, err = executeCommand(rootCmd, CompRequestCmd, "child", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"prod\tProduction\tclusters\t",
+		"main.go\t\t\tfile",
+		"plain\tA plain value\t\t",
+		":0",
+		"Completion ended with directive: BashCompDirectiveDefault", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// Subcommands and flags are grouped; descriptions can be omitted
+	output, err = executeCommand(rootCmd, CompNoDescRequestCmd, "ch")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"child\t\tcommands\tcommand",
+		":0",
+		"Completion ended with directive: BashCompDirectiveDefault", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	output, err = executeCommand(rootCmd, CompRequestCmd, "child", "--verb")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"--verbose\tbe verbose\tflags\tflag",
+		":0",
+		"Completion ended with directive: BashCompDirectiveDefault", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestCompletionString(t *testing.T) {
+	testcases := []struct {
+		comp     Completion
+		expected string
+	}{
+		{Completion{Value: "a"}, "a"},
+		{Completion{Value: "a", Description: "desc"}, "a\tdesc"},
+		{Completion{Value: "a", Group: "group"}, "a\t\tgroup"},
+		{Completion{Value: "a", Description: "desc", Group: "group", Kind: CompletionKindValue}, "a\tdesc\tgroup\tvalue"},
+	}
+	for _, tc := range testcases {
+		if got := tc.comp.String(); got != tc.expected {
+			t.Errorf("expected: %q, got: %q", tc.expected, got)
+		}
+		if got := parseCompletion(tc.comp.String()); got != tc.comp {
+			t.Errorf("expected: %#v, got: %#v", tc.comp, got)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package cobra
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompletionHooks(t *testing.T) {
+	var calls []string
+	defer func(saved, savedRun, savedCompletion []func()) {
+		initializers, runInitializers, completionInitializers = saved, savedRun, savedCompletion
+	}(initializers, runInitializers, completionInitializers)
+	initializers, runInitializers, completionInitializers = nil, nil, nil
+	OnInitialize(func() { calls = append(calls, "init") })
+	OnRunInitialize(func() { calls = append(calls, "runInit") })
+	OnCompletionInitialize(func() { calls = append(calls, "completionInit") })
+
+	rootCmd := &Command{
+		Use: "root",
+		PersistentPreRun: func(cmd *Command, args []string) {
+			config, _ := cmd.Flags().GetString("config")
+			calls = append(calls, "preRun:"+cmd.Name()+":"+config)
+			if !cmd.IsCompletionRequest() {
+				calls = append(calls, "sideEffect")
+			}
+		},
+		Run: emptyRun,
+	}
+	rootCmd.PersistentFlags().String("config", "", "config file")
+	childCmd := &Command{
+		Use: "child",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			calls = append(calls, "complete")
+			return []string{"one"}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	rootCmd.AddCommand(childCmd)
+
+	// The initializers and the hook run by default, for the command being
+	// completed and with its flags parsed
+	if _, err := executeCommand(rootCmd, CompRequestCmd, "child", "--config", "app.yaml", ""); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := "init,completionInit,preRun:child:app.yaml,complete"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	// Opting out, the hook does not run
+	calls = nil
+	rootCmd.SkipPersistentPreRunOnCompletion = true
+	if _, err := executeCommand(rootCmd, CompRequestCmd, "child", "--config", "app.yaml", ""); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = "init,completionInit,complete"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	// Completion hooks do not run when executing a command, unlike the others
+	calls = nil
+	if _, err := executeCommand(rootCmd, "child", "--config", "run.yaml"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = "init,runInit,preRun:child:run.yaml,sideEffect"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+
+	// The hook of a child that opts out overrides the one of its parent
+	calls = nil
+	rootCmd.SkipPersistentPreRunOnCompletion = false
+	childCmd.SkipPersistentPreRunOnCompletion = true
+	childCmd.PersistentPreRun = func(cmd *Command, args []string) {
+		calls = append(calls, "childPreRun")
+	}
+	if _, err := Complete(rootCmd, []string{"child"}, 1); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = "init,completionInit,complete"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Errorf("expected: %q, got: %q", expected, got)
+	}
+	if rootCmd.IsCompletionRequest() {
+		t.Error("expected the completion request to be over")
+	}
+}
+
+func TestCompletionHooksError(t *testing.T) {
+	rootCmd := &Command{
+		Use: "root",
+		PersistentPreRunE: func(cmd *Command, args []string) error {
+			return errors.New("no configuration")
+		},
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			return []string{"one"}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+
+	output, err := executeCommand(rootCmd, CompNoDescRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "Completion ended with directive: BashCompDirectiveError")
+	checkStringOmits(t, output, "one")
+}
+
+func TestCompletionHooksCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-comp-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CompletionCacheDir = dir
+	defer func() { CompletionCacheDir = "" }()
+
+	hooks := 0
+	rootCmd := &Command{
+		Use:              "root",
+		PersistentPreRun: func(cmd *Command, args []string) { hooks++ },
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			return []string{"one"}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	rootCmd.SetCompletionCacheTTL(time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if _, err := Complete(rootCmd, nil, 0); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	// The hook does not run when the cached completions are used
+	if hooks != 1 {
+		t.Errorf("expected the hook to run once, got %d", hooks)
+	}
+}
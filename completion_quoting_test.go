@@ -0,0 +1,341 @@
+package cobra
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hostileValues returns the completion values of the fixture corpus, which
+// contain spaces and shell metacharacters.
+func hostileValues(t *testing.T) []string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "completion_values.json"))
+	if err != nil {
+		t.Fatalf("Unable to read the fixture corpus: %v", err)
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		t.Fatalf("Unable to parse the fixture corpus: %v", err)
+	}
+	return values
+}
+
+// hostileCompletionOutput returns the __complete output, in the latest format,
+// for a program named testprog completing the corpus values as arguments.
+func hostileCompletionOutput(t *testing.T, values []string, compCmd string) string {
+	rootCmd := &Command{
+		Use:  "testprog",
+		Args: ArbitraryArgs,
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			var comps []string
+			for _, value := range values {
+				comps = append(comps, Completion{Value: value, Description: "about " + value}.String())
+			}
+			return comps, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	os.Setenv(CompFormatEnvVar, "3")
+	defer os.Unsetenv(CompFormatEnvVar)
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(new(bytes.Buffer))
+	rootCmd.SetArgs([]string{compCmd, ""})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+// runShell runs script with the given shell, skipping the test if the
+// shell is not installed, and returns the NUL-separated strings it printed.
+func runShell(t *testing.T, shell string, script string) []string {
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		t.Skipf("%s is not installed", shell)
+	}
+	cmd := exec.Command(path, "-c", script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%s failed: %v\n%s", shell, err, stderr.String())
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+}
+
+// writeTempFile writes content to a file of dir and returns its path.
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Unable to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestCompletionEscaping(t *testing.T) {
+	values := hostileValues(t)
+	output := hostileCompletionOutput(t, values, CompRequestCmd)
+
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if lines[len(lines)-1] != ":4" {
+		t.Fatalf("expected the directive last, got: %q", output)
+	}
+	lines = lines[:len(lines)-1]
+	if len(lines) != len(values) {
+		t.Fatalf("expected one line per value, got: %q", output)
+	}
+	for i, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			t.Errorf("expected 4 fields, got: %q", line)
+			continue
+		}
+		if got := unescapeCompField(fields[0]); got != values[i] {
+			t.Errorf("expected value: %q, got: %q", values[i], got)
+		}
+		if got := unescapeCompField(fields[1]); got != "about "+values[i] {
+			t.Errorf("expected description: %q, got: %q", "about "+values[i], got)
+		}
+	}
+
+	// Tabs are escaped as well
+	comp := formatCompletion(Completion{Value: "a\\b", Description: "c\td\ne"}, 3, true)
+	if expected := "a\\\\b\tc\\td\\ne\t\t"; comp != expected {
+		t.Errorf("expected: %q, got: %q", expected, comp)
+	}
+}
+
+func TestBashCompletionQuoting(t *testing.T) {
+	values := hostileValues(t)
+	dir, err := ioutil.TempDir("", "cobra-bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootCmd := &Command{Use: "testprog", ValidArgsFunction: validArgsFunc, Run: emptyRun}
+	script := new(bytes.Buffer)
+	rootCmd.GenBashCompletion(script)
+	scriptPath := writeTempFile(t, dir, "completion.bash", script.String())
+	outPath := writeTempFile(t, dir, "complete.out", hostileCompletionOutput(t, values, CompNoDescRequestCmd))
+
+	// Each completion inserted in the command-line must be read back by bash as the value
+	complete := func(cur string) []string {
+		return runShell(t, "bash", `
+if ! declare -F _get_comp_words_by_ref >/dev/null; then
+    _get_comp_words_by_ref() {
+        cur=${COMP_WORDS[COMP_CWORD]}
+        prev=${COMP_WORDS[COMP_CWORD-1]}
+        words=("${COMP_WORDS[@]}")
+        cword=${COMP_CWORD}
+    }
+fi
+source "`+scriptPath+`"
+testprog() { cat "`+outPath+`"; }
+COMP_WORDS=(testprog '`+cur+`')
+COMP_CWORD=1
+__start_testprog 2>/dev/null
+for comp in "${COMPREPLY[@]}"; do
+    eval "value=${comp}"
+    printf '%s\0' "${value}"
+done
+`)
+	}
+	if results := complete(""); !reflect.DeepEqual(results, values) {
+		t.Errorf("expected: %q, got: %q", values, results)
+	}
+
+	// The word being completed is filtered on once dequoted
+	expected := []string{"with space"}
+	if results := complete(`with\ `); !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected: %q, got: %q", expected, results)
+	}
+	if results := complete(`"with "`); !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected: %q, got: %q", expected, results)
+	}
+}
+
+func TestZshCompletionQuoting(t *testing.T) {
+	values := hostileValues(t)
+	dir, err := ioutil.TempDir("", "cobra-zsh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootCmd := &Command{Use: "testprog", Run: emptyRun}
+	script := new(bytes.Buffer)
+	rootCmd.GenZshCompletionV2(script, true)
+	scriptPath := writeTempFile(t, dir, "completion.zsh", script.String())
+	outPath := writeTempFile(t, dir, "complete.out", hostileCompletionOutput(t, values, CompRequestCmd))
+
+	// _describe receives each value, with its backslashes and colons escaped,
+	// followed by its description
+	results := runShell(t, "zsh", `
+setopt extendedglob
+compdef() { :; }
+_describe() {
+    local comp
+    for comp in "${(@P)4}"; do
+        comp=${comp%%:about *}
+        print -rn -- "${comp//(#b)\\(?)/${match[1]}}"
+        printf '\0'
+    done
+}
+source "`+scriptPath+`"
+testprog() { cat "`+outPath+`"; }
+words=(testprog "")
+CURRENT=2
+_testprog
+`)
+	if !reflect.DeepEqual(results, values) {
+		t.Errorf("expected: %q, got: %q", values, results)
+	}
+}
+
+func TestFishCompletionQuoting(t *testing.T) {
+	values := hostileValues(t)
+	dir, err := ioutil.TempDir("", "cobra-fish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootCmd := &Command{Use: "testprog", Run: emptyRun}
+	script := new(bytes.Buffer)
+	rootCmd.GenFishCompletion(script, true)
+	scriptPath := writeTempFile(t, dir, "completion.fish", script.String())
+	outPath := writeTempFile(t, dir, "complete.out", hostileCompletionOutput(t, values, CompRequestCmd))
+
+	// Fish inserts the value preceding the tab, escaping it itself
+	results := runShell(t, "fish", `
+source "`+scriptPath+`"
+function testprog; cat "`+outPath+`"; end
+set __testprog_comp_commandLine "testprog "
+__testprog_prepare_completions
+for comp in $__testprog_comp_results
+    printf '%s\0' (string split -m 1 -- \t $comp)[1]
+end
+`)
+	var expected []string
+	for _, value := range values {
+		// Fish cannot list values containing a newline
+		if !strings.Contains(value, "\n") {
+			expected = append(expected, value)
+		}
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected: %q, got: %q", expected, results)
+	}
+}
+
+func TestPowerShellCompletionQuoting(t *testing.T) {
+	values := hostileValues(t)
+	dir, err := ioutil.TempDir("", "cobra-pwsh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootCmd := &Command{Use: "testprog", Run: emptyRun}
+	script := new(bytes.Buffer)
+	rootCmd.GenPowerShellCompletion(script)
+	scriptPath := writeTempFile(t, dir, "completion.ps1", script.String())
+	outPath := writeTempFile(t, dir, "complete.out", hostileCompletionOutput(t, values, CompRequestCmd))
+	// The completer is only called for native commands
+	progPath := writeTempFile(t, dir, "testprog", "#!/bin/sh\ncat '"+outPath+"'\n")
+	if err := os.Chmod(progPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each completion inserted in the command-line must be read back by PowerShell as the value
+	results := runShell(t, "pwsh", `
+. '`+scriptPath+`'
+$env:PATH = '`+dir+`' + [IO.Path]::PathSeparator + $env:PATH
+function __echo { $args[0] }
+$result = TabExpansion2 -inputScript 'testprog ' -cursorColumn 9
+foreach ($match in $result.CompletionMatches) {
+    $value = Invoke-Expression "__echo $($match.CompletionText)"
+    [Console]::Out.Write($value + [char]0)
+}
+`)
+	if !reflect.DeepEqual(results, values) {
+		t.Errorf("expected: %q, got: %q", values, results)
+	}
+}
+
+// TestCompletionScriptsFormat checks, without running the shells, that every
+// script requests the escaped format and decodes each field it uses.
+func TestCompletionScriptsFormat(t *testing.T) {
+	rootCmd := &Command{Use: "testprog", Run: emptyRun}
+	tcs := []struct {
+		shell    string
+		gen      func(*bytes.Buffer) error
+		expected []string
+	}{
+		{
+			shell: "bash",
+			gen:   func(buf *bytes.Buffer) error { return rootCmd.GenBashCompletion(buf) },
+			expected: []string{
+				"local -x COBRA_COMPLETION_FORMAT=3",
+			},
+		},
+		{
+			shell: "zsh",
+			gen:   func(buf *bytes.Buffer) error { return rootCmd.GenZshCompletionV2(buf, true) },
+			expected: []string{
+				"local -x COBRA_COMPLETION_FORMAT=3",
+				`fields=("${(@ps:\t:)comp}")`,
+				"comp=${(g::)fields[1]}",
+				"desc=${(g::)fields[2]}",
+				"group=${(g::)fields[3]}",
+			},
+		},
+		{
+			shell: "fish",
+			gen:   func(buf *bytes.Buffer) error { return rootCmd.GenFishCompletion(buf, true) },
+			expected: []string{
+				"set -lx COBRA_COMPLETION_FORMAT 3",
+				"function __testprog_unescape",
+				`set value (__testprog_unescape "$fields[1]")`,
+				`set desc (__testprog_unescape "$fields[2]"`,
+				`set group (__testprog_unescape "$fields[3]"`,
+			},
+		},
+		{
+			shell: "powershell",
+			gen:   func(buf *bytes.Buffer) error { return rootCmd.GenPowerShellCompletion(buf) },
+			expected: []string{
+				"$env:COBRA_COMPLETION_FORMAT = 3",
+				"$env:COBRA_COMPLETION_FORMAT = $PreviousFormat",
+				"function __testprog_unescape",
+				`[regex]::Replace($Field, '\\(.)'`,
+				"$Name = __testprog_unescape $Fields[0]",
+				"__testprog_unescape $Fields[1]",
+				"__testprog_unescape $Fields[2]",
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.shell, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := tc.gen(buf); err != nil {
+				t.Fatal(err)
+			}
+			for _, expected := range tc.expected {
+				checkStringContains(t, buf.String(), expected)
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package cobra
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/spf13/pflag"
 )
@@ -38,6 +40,31 @@ const (
 	// This currently does not work for zsh or bash < 4
 	BashCompDirectiveNoFileComp
 
+	// BashCompDirectiveFilterFileExt indicates that the provided completions
+	// should be used as file extension filters.
+	// For example, to complete only files of the form *.json or *.yaml:
+	//    return []string{"yaml", "json"}, BashCompDirectiveFilterFileExt
+	// For fish, this currently performs file completion without filtering.
+	BashCompDirectiveFilterFileExt
+
+	// BashCompDirectiveFilterDirs indicates that only directory names should
+	// be provided in file completion.  To request directory names within another
+	// directory, the returned completions should specify the directory within
+	// which to search.
+	// For fish, this currently performs file completion without filtering.
+	BashCompDirectiveFilterDirs
+
+	// BashCompDirectiveTimeout indicates that the completion deadline was reached
+	// before all completions could be gathered.  The completions provided are the
+	// ones gathered so far, and the shell should not wait for more.
+	BashCompDirectiveTimeout
+
+	// ===========================================================================
+
+	// All directives using iota should be above this one.
+	// For internal use.
+	bashCompDirectiveMaxValue
+
 	// BashCompDirectiveDefault indicates to let the shell perform its default
 	// behavior after completions have been provided.
 	BashCompDirectiveDefault BashCompDirective = 0
@@ -68,11 +95,20 @@ func (d BashCompDirective) string() string {
 	if d&BashCompDirectiveNoFileComp != 0 {
 		directives = append(directives, "BashCompDirectiveNoFileComp")
 	}
+	if d&BashCompDirectiveFilterFileExt != 0 {
+		directives = append(directives, "BashCompDirectiveFilterFileExt")
+	}
+	if d&BashCompDirectiveFilterDirs != 0 {
+		directives = append(directives, "BashCompDirectiveFilterDirs")
+	}
+	if d&BashCompDirectiveTimeout != 0 {
+		directives = append(directives, "BashCompDirectiveTimeout")
+	}
 	if len(directives) == 0 {
 		directives = append(directives, "BashCompDirectiveDefault")
 	}
 
-	if d > BashCompDirectiveError+BashCompDirectiveNoSpace+BashCompDirectiveNoFileComp {
+	if d >= bashCompDirectiveMaxValue {
 		return fmt.Sprintf("ERROR: unexpected BashCompDirective value: %d", d)
 	}
 	return strings.Join(directives, ", ")
@@ -88,6 +124,8 @@ func (c *Command) initCompleteCmd(args []string) {
 		DisableFlagParsing:    true,
 		Args:                  MinimumNArgs(1),
 		Short:                 "Request shell completion choices for the specified command-line",
+		// The hooks of the command being completed run instead, see runCompletionHooks
+		PersistentPreRun: func(cmd *Command, args []string) {},
 		Long: fmt.Sprintf("%[2]s is a special command that is used by the shell completion logic\n%[1]s",
 			"to request completion choices for the specified command-line.", CompRequestCmd),
 		Run: func(cmd *Command, args []string) {
@@ -99,17 +137,14 @@ func (c *Command) initCompleteCmd(args []string) {
 				// 2- Even without completions, we need to print the directive
 			}
 
-			noDescriptions := (cmd.CalledAs() == CompNoDescRequestCmd)
+			includeDesc := (cmd.CalledAs() != CompNoDescRequestCmd)
+			format := requestedCompFormat()
 			for _, comp := range completions {
-				if noDescriptions {
-					// Remove any description that may be included following a tab character.
-					comp = strings.Split(comp, "\t")[0]
-				}
 				// Print each possible completion to stdout for the completion script to consume.
-				fmt.Fprintln(finalCmd.OutOrStdout(), comp)
+				fmt.Fprintln(finalCmd.OutOrStdout(), formatCompletion(parseCompletion(comp), format, includeDesc))
 			}
 
-			if directive > BashCompDirectiveError+BashCompDirectiveNoSpace+BashCompDirectiveNoFileComp {
+			if directive >= bashCompDirectiveMaxValue {
 				directive = BashCompDirectiveDefault
 			}
 
@@ -125,7 +160,8 @@ func (c *Command) initCompleteCmd(args []string) {
 	}
 	c.AddCommand(completeCmd)
 	subCmd, _, err := c.Find(args)
-	if err != nil || subCmd.Name() != CompRequestCmd {
+	c.completionRequest = err == nil && subCmd.Name() == CompRequestCmd
+	if !c.completionRequest {
 		// Only create this special command if it is actually being called.
 		// This reduces possible side-effects of creating such a command;
 		// for example, having this command would cause problems to a
@@ -135,6 +171,123 @@ func (c *Command) initCompleteCmd(args []string) {
 	}
 }
 
+// Completion is a single completion choice.
+type Completion struct {
+	// Value is the text to insert in the command-line.
+	Value string
+	// Description is an optional description of the choice.
+	Description string
+	// Group is an optional name under which shells supporting it list the choice.
+	Group string
+	// Kind is an optional indication of what the choice designates.
+	Kind CompletionKind
+}
+
+// CompletionResult holds the completion choices for a command-line
+// and the directive describing how the shell should handle them.
+type CompletionResult struct {
+	Completions []Completion
+	Directive   BashCompDirective
+}
+
+// Complete computes the completion choices for a command-line of the program
+// whose root command is root, as the hidden __complete command would.
+// The words are the arguments of the command-line, not including the program name,
+// and cursor is the index of the word being completed.  If cursor is len(words),
+// a new empty word is being completed.  Words following the cursor are ignored.
+// As with __complete, completions found before an error occurred are still returned
+// along with the error.  The flags parsed from words are reset once done, so that
+// the command tree can then be executed; slice flags whose values do not implement
+// the Replace method of the SliceValue interface of pflag are only marked unchanged.
+// Since the completion functions read the flags, Complete does not return before
+// a completion function that passed the CompletionOptions.Timeout deadline has
+// returned as well; its late completions are still discarded.
+func Complete(root *Command, words []string, cursor int) (CompletionResult, error) {
+	if cursor < 0 || cursor > len(words) {
+		return CompletionResult{}, fmt.Errorf("cursor %d out of range for %d words", cursor, len(words))
+	}
+	args := make([]string, 0, cursor+1)
+	args = append(args, words[:cursor]...)
+	if cursor < len(words) {
+		args = append(args, words[cursor])
+	} else {
+		args = append(args, "")
+	}
+
+	// Make the default commands available as they would be during execution
+	root.InitDefaultHelpCmd()
+	root.InitDefaultCompletionCmd()
+
+	root.completionRequest = true
+	restoreFlags := saveFlags(root)
+	defer func() {
+		// After a timeout, the completion function may still be running and
+		// reading the flags: wait for it before restoring them
+		completionCallsOf(root).Wait()
+		restoreFlags()
+		root.completionRequest = false
+	}()
+
+	_, comps, directive, err := root.getCompletions(args)
+	if directive >= bashCompDirectiveMaxValue {
+		directive = BashCompDirectiveDefault
+	}
+
+	result := CompletionResult{Directive: directive}
+	for _, comp := range comps {
+		result.Completions = append(result.Completions, parseCompletion(comp))
+	}
+	return result, err
+}
+
+// flagState is the state of a flag before completion.
+type flagState struct {
+	value   string
+	slice   []string
+	changed bool
+}
+
+// saveFlags saves the state of the flags of the command tree of root and
+// returns a function restoring the flags which were changed since.
+func saveFlags(root *Command) func() {
+	states := map[*pflag.Flag]flagState{}
+	save := func(flag *pflag.Flag) {
+		state := flagState{value: flag.Value.String(), changed: flag.Changed}
+		if slice, ok := flag.Value.(sliceValue); ok {
+			state.slice = append([]string(nil), slice.GetSlice()...)
+		}
+		states[flag] = state
+	}
+	var visit func(cmd *Command)
+	visit = func(cmd *Command) {
+		cmd.Flags().VisitAll(save)
+		cmd.PersistentFlags().VisitAll(save)
+		for _, sub := range cmd.Commands() {
+			visit(sub)
+		}
+	}
+	visit(root)
+
+	return func() {
+		for flag, state := range states {
+			if flag.Changed == state.changed && flag.Value.String() == state.value {
+				continue
+			}
+			var err error
+			if slice, ok := flag.Value.(sliceValue); ok {
+				err = slice.Replace(state.slice)
+			} else if !strings.HasSuffix(flag.Value.Type(), "Slice") && !strings.HasSuffix(flag.Value.Type(), "Array") {
+				// Setting the value of a slice again would append to it
+				err = flag.Value.Set(state.value)
+			}
+			if err != nil {
+				CompDebugln(fmt.Sprintf("Unable to reset flag --%s: %v", flag.Name, err), false)
+			}
+			flag.Changed = state.changed
+		}
+	}
+}
+
 func (c *Command) getCompletions(args []string) (*Command, []string, BashCompDirective, error) {
 	var completions []string
 
@@ -150,6 +303,15 @@ func (c *Command) getCompletions(args []string) (*Command, []string, BashCompDir
 		return c, completions, BashCompDirectiveDefault, fmt.Errorf("Unable to find a command for arguments: %v", trimmedArgs)
 	}
 
+	if finalCmd.DisableFlagParsing {
+		// The command receives its arguments as is, flags included, which
+		// are all forwarded to the program completion is delegated to
+		if delegateFn := finalCmd.completionDelegateFunc(len(finalArgs) + 1); delegateFn != nil {
+			comps, directive := c.runCompletionFunc(finalCmd, withCompletionHooks(delegateFn), nil, finalArgs, toComplete)
+			return finalCmd, comps, directive, nil
+		}
+	}
+
 	// When doing completion of a flag name, as soon as an argument starts with
 	// a '-' we know it is a flag.  We cannot use isFlagArg() here as it requires
 	// the flag to be complete
@@ -186,27 +348,19 @@ func (c *Command) getCompletions(args []string) (*Command, []string, BashCompDir
 		// Complete subcommand names
 		for _, subCmd := range finalCmd.Commands() {
 			if subCmd.IsAvailableCommand() && strings.HasPrefix(subCmd.Name(), toComplete) {
-				completions = append(completions, fmt.Sprintf("%s\t%s", subCmd.Name(), subCmd.Short))
+				completions = append(completions, Completion{
+					Value:       subCmd.Name(),
+					Description: subCmd.Short,
+					Group:       compGroupCommands,
+					Kind:        CompletionKindCommand,
+				}.String())
 			}
 		}
 
-		if len(finalCmd.ValidArgs) > 0 {
-			// Always complete ValidArgs, even if we are completing a subcommand name.
-			// This is for commands that have both subcommands and ValidArgs.
-			for _, validArg := range finalCmd.ValidArgs {
-				if strings.HasPrefix(validArg, toComplete) {
-					completions = append(completions, validArg)
-				}
-			}
-
-			// If there are ValidArgs specified (even if they don't match), we stop completion.
-			// Only one of ValidArgs or ValidArgsFunction can be used for a single command.
-			return finalCmd, completions, BashCompDirectiveNoFileComp, nil
-		}
-
-		// Always let the logic continue so as to add any ValidArgsFunction completions,
-		// even if we already found sub-commands.
-		// This is for commands that have subcommands but also specify a ValidArgsFunction.
+		// Always let the logic continue so as to add any ValidArgs or ValidArgsFunction
+		// completions, even if we already found sub-commands.
+		// This is for commands that have subcommands but also specify ValidArgs or
+		// a ValidArgsFunction.
 	}
 
 	// Parse the flags and extract the arguments to prepare for calling the completion function
@@ -224,8 +378,41 @@ func (c *Command) getCompletions(args []string) (*Command, []string, BashCompDir
 	var completionFn func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective)
 	if flag != nil {
 		completionFn = flagCompletionFunctions[flag]
+		if completionFn == nil {
+			// Enum flags provide their allowed values if no completion function was registered
+			completionFn = enumFlagCompletionFunc(flag)
+		}
+		if completionFn != nil && isMultiValueFlag(flag) {
+			completionFn = multiValueCompletionFunc(completionFn)
+		}
 	} else {
-		completionFn = finalCmd.ValidArgsFunction
+		// Delegating completion to another program, or a completion registered
+		// for this argument position, takes precedence
+		completionFn = finalCmd.completionDelegateFunc(len(finalArgs) + 1)
+		if completionFn == nil {
+			completionFn = finalCmd.positionalArgCompletionFunc(len(finalArgs) + 1)
+		}
+		if completionFn == nil && len(finalCmd.ValidArgs) > 0 {
+			for _, validArg := range finalCmd.ValidArgs {
+				if finalCmd.VariadicValidArgs && stringInSlice(validArg, finalArgs) {
+					// Each value can only be given once
+					continue
+				}
+				if strings.HasPrefix(validArg, toComplete) {
+					completions = append(completions, validArg)
+				}
+			}
+
+			// If there are ValidArgs specified (even if they don't match), we stop completion.
+			// Only one of ValidArgs or ValidArgsFunction can be used for a single command.
+			return finalCmd, completions, BashCompDirectiveNoFileComp, nil
+		}
+		if completionFn == nil {
+			completionFn = finalCmd.ValidArgsFunction
+		}
+		if completionFn != nil && finalCmd.VariadicValidArgs {
+			completionFn = variadicArgsCompletionFunc(completionFn)
+		}
 	}
 	if completionFn == nil {
 		// Go custom completion not supported/needed for this flag or command
@@ -233,11 +420,233 @@ func (c *Command) getCompletions(args []string) (*Command, []string, BashCompDir
 	}
 
 	// Call the registered completion function to get the completions
-	comps, directive := completionFn(finalCmd, finalArgs, toComplete)
+	comps, directive := c.runCompletionFunc(finalCmd, withCompletionHooks(completionFn), flag, finalArgs, toComplete)
 	completions = append(completions, comps...)
 	return finalCmd, completions, directive, nil
 }
 
+// IsCompletionRequest returns true if the program is computing completions,
+// through the hidden __complete command or Complete, rather than running a
+// command.  Lifecycle hooks can use it to avoid side effects during completion.
+func (c *Command) IsCompletionRequest() bool {
+	return c.Root().completionRequest
+}
+
+// withCompletionHooks returns completionFn preceded by the hooks which apply to
+// the command being completed, so that they do not run when cached completions
+// are used.  If a hook fails, the error is reported and no completions are returned.
+func withCompletionHooks(completionFn func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective)) func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+	return func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		if err := cmd.runCompletionHooks(args); err != nil {
+			CompErrorln(err.Error())
+			return nil, BashCompDirectiveError
+		}
+		return completionFn(cmd, args, toComplete)
+	}
+}
+
+// runCompletionHooks runs, before completion functions are called for the
+// command, the OnInitialize and OnCompletionInitialize functions followed by
+// the PersistentPreRun hook that applies to it, unless the command defining
+// the hook sets SkipPersistentPreRunOnCompletion.
+func (c *Command) runCompletionHooks(args []string) error {
+	for _, x := range initializers {
+		x()
+	}
+	for _, x := range completionInitializers {
+		x()
+	}
+	for p := c; p != nil; p = p.Parent() {
+		if p.PersistentPreRunE == nil && p.PersistentPreRun == nil {
+			continue
+		}
+		if p.SkipPersistentPreRunOnCompletion {
+			return nil
+		}
+		if p.PersistentPreRunE != nil {
+			return p.PersistentPreRunE(c, args)
+		}
+		p.PersistentPreRun(c, args)
+		return nil
+	}
+	return nil
+}
+
+// runCompletionFunc calls the completion function of finalCmd, or of one of its
+// flags, within the completion context.  If the deadline of the context passes
+// first, the completions reported so far through ReportCompletions are returned
+// along with BashCompDirectiveTimeout.
+func (c *Command) runCompletionFunc(finalCmd *Command, completionFn func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective), flag *pflag.Flag, args []string, toComplete string) ([]string, BashCompDirective) {
+	ctx, cancel := c.completionContext()
+	defer cancel()
+	state := &completionState{}
+	ctx = context.WithValue(ctx, completionStateKey{}, state)
+
+	// The context is only visible through cmd.Context() while the function
+	// runs, even after a timeout, so that the command keeps its own context
+	// once completion is done
+	setCompletionContext(finalCmd, ctx)
+
+	if ctx.Done() == nil {
+		// The context can never be done, no need to watch it
+		defer clearCompletionContext(finalCmd, ctx)
+		return finalCmd.callCompletionFunc(completionFn, flag, args, toComplete)
+	}
+
+	type result struct {
+		comps     []string
+		directive BashCompDirective
+	}
+	done := make(chan result, 1)
+	calls := completionCallsOf(c.Root())
+	calls.Add(1)
+	go func() {
+		defer calls.Done()
+		defer clearCompletionContext(finalCmd, ctx)
+		comps, directive := finalCmd.callCompletionFunc(completionFn, flag, args, toComplete)
+		done <- result{comps, directive}
+	}()
+
+	select {
+	case res := <-done:
+		return res.comps, res.directive
+	case <-ctx.Done():
+		// Return what was gathered so far and tell the shell not to wait for more
+		CompDebugln(fmt.Sprintf("Completion function did not complete: %v", ctx.Err()), false)
+		return state.reported(), BashCompDirectiveTimeout
+	}
+}
+
+// completionContexts holds the contexts of the completion requests whose
+// completion functions are running, per command, and completionCalls the
+// completion functions running in the background, per root command.
+var (
+	completionContextsMu sync.Mutex
+	completionContexts   = map[*Command]context.Context{}
+	completionCalls      = map[*Command]*sync.WaitGroup{}
+)
+
+// setCompletionContext makes ctx the context of cmd while its completion
+// functions run.
+func setCompletionContext(cmd *Command, ctx context.Context) {
+	completionContextsMu.Lock()
+	defer completionContextsMu.Unlock()
+	completionContexts[cmd] = ctx
+}
+
+// clearCompletionContext restores the context of cmd once the completion
+// function called with ctx has returned, unless another request replaced it.
+func clearCompletionContext(cmd *Command, ctx context.Context) {
+	completionContextsMu.Lock()
+	defer completionContextsMu.Unlock()
+	if completionContexts[cmd] == ctx {
+		delete(completionContexts, cmd)
+	}
+}
+
+// completionCallsOf returns the completion functions of the command tree of
+// root running in the background.
+func completionCallsOf(root *Command) *sync.WaitGroup {
+	completionContextsMu.Lock()
+	defer completionContextsMu.Unlock()
+	calls, ok := completionCalls[root]
+	if !ok {
+		calls = &sync.WaitGroup{}
+		completionCalls[root] = calls
+	}
+	return calls
+}
+
+// completionContextOf returns the context of the running completion request
+// of cmd, or nil if none is running.
+func completionContextOf(cmd *Command) context.Context {
+	completionContextsMu.Lock()
+	defer completionContextsMu.Unlock()
+	return completionContexts[cmd]
+}
+
+type completionStateKey struct{}
+
+// completionState is the state of a completion request shared by the
+// completion function and Cobra while the function runs.
+type completionState struct {
+	mu sync.Mutex
+	// comps holds the completions reported by the function before it returns.
+	comps []string
+	// chosen holds the values already chosen for the slice flag or the
+	// variadic arguments being completed.
+	chosen []string
+	// filter is applied to the reported completions, as the wrappers of the
+	// completion function would to its results.
+	filter func([]string) []string
+}
+
+// completionStateOf returns the state of the completion request of cmd,
+// or nil if no completion function of cmd is running.
+func completionStateOf(cmd *Command) *completionState {
+	if ctx := cmd.Context(); ctx != nil {
+		if state, ok := ctx.Value(completionStateKey{}).(*completionState); ok {
+			return state
+		}
+	}
+	return nil
+}
+
+// ReportCompletions records completions found so far by a completion function,
+// to be called with the context returned by cmd.Context().  Completion functions
+// gathering their completions in several steps can report each step, so that
+// if the CompletionOptions.Timeout deadline passes before they return, the
+// completions reported are returned to the shell.  The completions returned by
+// the function replace the reported ones once it returns.
+func ReportCompletions(ctx context.Context, completions ...string) {
+	if state, ok := ctx.Value(completionStateKey{}).(*completionState); ok {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		state.comps = append(state.comps, completions...)
+	}
+}
+
+// setChosen sets the values already chosen and the function filtering the
+// reported completions accordingly.
+func (s *completionState) setChosen(chosen []string, filter func([]string) []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chosen = chosen
+	s.filter = filter
+}
+
+// chosenValues returns the values already chosen.
+func (s *completionState) chosenValues() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chosen
+}
+
+// reported returns the completions reported so far.
+func (s *completionState) reported() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comps := append([]string(nil), s.comps...)
+	if s.filter != nil {
+		comps = s.filter(comps)
+	}
+	return comps
+}
+
+// completionContext returns the context in which completion functions are called.
+// It is derived from the context of the command and expires after the
+// CompletionOptions.Timeout of the root command, if one is set.
+func (c *Command) completionContext() (context.Context, context.CancelFunc) {
+	ctx := c.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout := c.Root().CompletionOptions.Timeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
 func getFlagNameCompletions(flag *pflag.Flag, toComplete string) []string {
 	if nonCompletableFlag(flag) {
 		return []string{}
@@ -247,23 +656,32 @@ func getFlagNameCompletions(flag *pflag.Flag, toComplete string) []string {
 	flagName := "--" + flag.Name
 	if strings.HasPrefix(flagName, toComplete) {
 		// Flag without the =
-		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+		completions = append(completions, flagNameCompletion(flagName, flag))
 
 		if len(flag.NoOptDefVal) == 0 {
 			// Flag requires a value, so it can be suffixed with =
 			flagName += "="
-			completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+			completions = append(completions, flagNameCompletion(flagName, flag))
 		}
 	}
 
 	flagName = "-" + flag.Shorthand
 	if len(flag.Shorthand) > 0 && strings.HasPrefix(flagName, toComplete) {
-		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+		completions = append(completions, flagNameCompletion(flagName, flag))
 	}
 
 	return completions
 }
 
+func flagNameCompletion(flagName string, flag *pflag.Flag) string {
+	return Completion{
+		Value:       flagName,
+		Description: flag.Usage,
+		Group:       compGroupFlags,
+		Kind:        CompletionKindFlag,
+	}.String()
+}
+
 func checkIfFlagCompletion(finalCmd *Command, args []string, lastArg string) (*pflag.Flag, []string, string, error) {
 	var flagName string
 	trimmedArgs := args
@@ -2,8 +2,11 @@ package cobra
 
 import (
 	"bytes"
+	"context"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func validArgsFunc(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
@@ -629,3 +632,285 @@ func TestFlagCompletionInGoWithDesc(t *testing.T) {
 		t.Errorf("expected: %q, got: %q", expected, output)
 	}
 }
+
+func TestCompletionFuncContext(t *testing.T) {
+	type ctxKey struct{}
+
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use: "child",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			if cmd.Context().Value(ctxKey{}) != "value" {
+				return nil, BashCompDirectiveError
+			}
+			if _, ok := cmd.Context().Deadline(); !ok {
+				return nil, BashCompDirectiveError
+			}
+			return []string{"one"}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+	rootCmd.AddCommand(childCmd)
+	rootCmd.CompletionOptions.Timeout = time.Minute
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	output, err := executeCommandWithContext(ctx, rootCmd, CompNoDescRequestCmd, "child", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"one",
+		":4",
+		"Completion ended with directive: BashCompDirectiveNoFileComp", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestCompletionTimeout(t *testing.T) {
+	rootCmd := &Command{
+		Use: "root",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			<-cmd.Context().Done()
+			time.Sleep(time.Second)
+			return []string{"late"}, BashCompDirectiveDefault
+		},
+		Run: emptyRun,
+	}
+	childCmd := &Command{Use: "child", Short: "The child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+	rootCmd.CompletionOptions.Timeout = 10 * time.Millisecond
+
+	output, err := executeCommand(rootCmd, CompNoDescRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// The subcommands gathered before the deadline are returned
+	expected := strings.Join([]string{
+		"child",
+		"completion",
+		":32",
+		"Completion ended with directive: BashCompDirectiveTimeout", ""}, "\n")
+
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestCompletionTimeoutReportedCompletions(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use:               "child",
+		VariadicValidArgs: true,
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			ReportCompletions(cmd.Context(), "one", "two")
+			<-cmd.Context().Done()
+			time.Sleep(time.Second)
+			return []string{"late"}, BashCompDirectiveDefault
+		},
+		Run: emptyRun,
+	}
+	childCmd.Flags().StringSlice("labels", nil, "labels")
+	childCmd.RegisterFlagCompletionFunc("labels", func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		ReportCompletions(cmd.Context(), "red", "green", "blue")
+		<-cmd.Context().Done()
+		time.Sleep(time.Second)
+		return []string{"late"}, BashCompDirectiveDefault
+	})
+	rootCmd.AddCommand(childCmd)
+	rootCmd.CompletionOptions.Timeout = 10 * time.Millisecond
+
+	// The completions reported before the deadline are returned
+	output, err := executeCommand(rootCmd, CompNoDescRequestCmd, "child", "one", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"two",
+		":32",
+		"Completion ended with directive: BashCompDirectiveTimeout", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// They are prefixed with the values already chosen
+	output, err = executeCommand(rootCmd, CompNoDescRequestCmd, "child", "--labels", "red,")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"red,green",
+		"red,blue",
+		":32",
+		"Completion ended with directive: BashCompDirectiveTimeout", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// The command does not keep the context of the completion request once
+	// the completion functions have returned
+	completionCallsOf(rootCmd).Wait()
+	if ctx := childCmd.Context(); ctx != nil {
+		t.Errorf("expected the command to have no context, got %v", ctx)
+	}
+}
+
+func TestCompletionTimeoutWithoutContext(t *testing.T) {
+	ctxErr := make(chan error, 1)
+	rootCmd := &Command{
+		Use: "root",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			<-cmd.Context().Done()
+			time.Sleep(50 * time.Millisecond)
+			// The context of the request is kept until the function returns
+			ctxErr <- cmd.Context().Err()
+			return []string{"late"}, BashCompDirectiveDefault
+		},
+		Run: emptyRun,
+	}
+	rootCmd.CompletionOptions.Timeout = 10 * time.Millisecond
+
+	// The command is executed without ExecuteContext
+	output, err := executeCommand(rootCmd, CompNoDescRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, ":32\n")
+	if err := <-ctxErr; err != context.DeadlineExceeded {
+		t.Errorf("expected the context to have expired, got %v", err)
+	}
+}
+
+func TestCompleteTimeout(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().String("namespace", "", "namespace")
+	namespace := make(chan string, 1)
+	rootCmd.ValidArgsFunction = func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		<-cmd.Context().Done()
+		time.Sleep(50 * time.Millisecond)
+		value, _ := cmd.Flags().GetString("namespace")
+		namespace <- value
+		return []string{"late"}, BashCompDirectiveDefault
+	}
+	rootCmd.CompletionOptions.Timeout = 10 * time.Millisecond
+
+	result, err := Complete(rootCmd, []string{"--namespace", "prod", ""}, 2)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if result.Directive != BashCompDirectiveTimeout || len(result.Completions) != 0 {
+		t.Errorf("expected no completions and a timeout, got %+v", result)
+	}
+	// The flags are only restored once the completion function has returned
+	if value := <-namespace; value != "prod" {
+		t.Errorf("expected the completion function to see the flag, got %q", value)
+	}
+	if value, _ := rootCmd.Flags().GetString("namespace"); value != "" {
+		t.Errorf("expected the flag to be restored, got %q", value)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	rootCmd := &Command{Use: "root", Args: NoArgs, Run: emptyRun}
+	childCmd := &Command{
+		Use:               "child",
+		Short:             "The child",
+		ValidArgsFunction: validArgsFunc,
+		Run:               emptyRun,
+	}
+	childCmd.Flags().Bool("verbose", false, "be verbose")
+	rootCmd.AddCommand(childCmd)
+
+	// Complete a new word after the child command
+	result, err := Complete(rootCmd, []string{"child"}, 1)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := CompletionResult{
+		Completions: []Completion{
+			{Value: "one", Description: "The first"},
+			{Value: "two", Description: "The second"},
+		},
+		Directive: BashCompDirectiveDefault,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, result)
+	}
+
+	// Complete a partial word; words after the cursor are ignored
+	result, err = Complete(rootCmd, []string{"chi", "ignored"}, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = CompletionResult{
+		Completions: []Completion{{Value: "child", Description: "The child", Group: "commands", Kind: CompletionKindCommand}},
+		Directive:   BashCompDirectiveDefault,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, result)
+	}
+
+	// Complete a flag name
+	result, err = Complete(rootCmd, []string{"child", "--verb"}, 1)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = CompletionResult{
+		Completions: []Completion{{Value: "--verbose", Description: "be verbose", Group: "flags", Kind: CompletionKindFlag}},
+		Directive:   BashCompDirectiveDefault,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected: %#v, got: %#v", expected, result)
+	}
+
+	if _, err = Complete(rootCmd, []string{"child"}, 2); err == nil {
+		t.Error("expected an error for a cursor out of range")
+	}
+	if _, err = Complete(rootCmd, []string{"child", "--unknown", ""}, 2); err == nil {
+		t.Error("expected an error for an unknown flag")
+	}
+}
+
+type contextKey struct{}
+
+func TestExecuteAfterComplete(t *testing.T) {
+	var verbose bool
+	var output string
+	var runCtx context.Context
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.PersistentFlags().StringVar(&output, "output", "text", "output format")
+	childCmd := &Command{
+		Use: "child",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			return []string{"one"}, BashCompDirectiveDefault
+		},
+		Run: func(cmd *Command, args []string) {
+			runCtx = cmd.Context()
+		},
+	}
+	childCmd.Flags().BoolVar(&verbose, "verbose", false, "be verbose")
+	rootCmd.AddCommand(childCmd)
+	rootCmd.CompletionOptions.Timeout = time.Second
+
+	if _, err := Complete(rootCmd, []string{"child", "--verbose", "--output", "json", ""}, 4); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if verbose || output != "text" || childCmd.Flags().Changed("verbose") {
+		t.Errorf("expected the flags to be reset, got verbose=%v output=%q", verbose, output)
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	rootCmd.SetArgs([]string{"child"})
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if runCtx == nil || runCtx.Value(contextKey{}) != "value" || runCtx.Err() != nil {
+		t.Errorf("expected the command to run with the context of ExecuteContext, got %v", runCtx)
+	}
+	if verbose || output != "text" {
+		t.Errorf("expected the flags to keep their defaults, got verbose=%v output=%q", verbose, output)
+	}
+}
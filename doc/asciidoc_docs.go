@@ -0,0 +1,311 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// asciidocCellEscaper escapes the content of an AsciiDoc table cell.
+var asciidocCellEscaper = strings.NewReplacer("|", "\\|")
+
+// asciidocLiteral returns s as inline literal monospace text, without substitutions.
+func asciidocLiteral(s string) string {
+	return "`+" + s + "+`"
+}
+
+// asciidocFlagName returns the name of a flag as shown in the options,
+// with its shorthand and the name of its value.
+func asciidocFlagName(flag *pflag.Flag) string {
+	varname, _ := pflag.UnquoteUsage(flag)
+	name := "--" + flag.Name
+	if varname != "" {
+		name += " " + varname
+	}
+	if len(flag.Shorthand) > 0 && len(flag.ShorthandDeprecated) == 0 {
+		return asciidocLiteral("-"+flag.Shorthand) + ", " + asciidocLiteral(name)
+	}
+	return asciidocLiteral(name)
+}
+
+func printOptionsAsciidoc(buf *bytes.Buffer, flags *pflag.FlagSet, title string) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	buf.WriteString("== " + title + "\n\n")
+	buf.WriteString("[cols=\"2,1,4\",options=\"header\"]\n|===\n|Flag |Default |Description\n\n")
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Deprecated) > 0 || flag.Hidden {
+			return
+		}
+		_, usage := pflag.UnquoteUsage(helpFlag(flag))
+		defValue := ""
+		if flag.DefValue != "" && flag.DefValue != "[]" {
+			defValue = asciidocLiteral(flag.DefValue)
+		}
+		buf.WriteString(fmt.Sprintf("|%s\n|%s\n|%s\n\n", asciidocFlagName(flag), defValue, asciidocCellEscaper.Replace(usage)))
+	})
+	buf.WriteString("|===\n\n")
+}
+
+// defaultAsciidocLinkHandler links to the page of a command with an Antora xref.
+func defaultAsciidocLinkHandler(name, ref string) string {
+	return fmt.Sprintf("xref:%s.adoc[%s]", ref, name)
+}
+
+// GenAsciidoc creates AsciiDoc output.
+func GenAsciidoc(cmd *cobra.Command, w io.Writer) error {
+	return GenAsciidocCustom(cmd, w, defaultAsciidocLinkHandler)
+}
+
+// GenAsciidocCustom creates custom AsciiDoc output. The linkHandler receives the
+// path of a command and the reference of its page, such as "root_sub", and
+// returns the cross-reference to it.
+func GenAsciidocCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string, string) string) error {
+	return genAsciidoc(cmd, w, defaultNaming, DefaultTreeFilter, linkHandler)
+}
+
+func genAsciidoc(cmd *cobra.Command, w io.Writer, naming NamingStrategy, filter func(*cobra.Command) bool, linkHandler func(string, string) string) error {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	buf := new(bytes.Buffer)
+	name := cmd.CommandPath()
+
+	short := cmd.Short
+	long := cmd.Long
+	if len(long) == 0 {
+		long = short
+	}
+	ref := naming(cmd)
+
+	buf.WriteString("[[" + ref + "]]\n")
+	buf.WriteString("= " + name + "\n\n")
+	buf.WriteString(short + "\n\n")
+	buf.WriteString("== Synopsis\n\n")
+	buf.WriteString(long + "\n\n")
+
+	if cmd.Runnable() {
+		buf.WriteString(fmt.Sprintf("[source,shell]\n----\n%s\n----\n\n", cmd.UseLine()))
+	}
+
+	if len(cmd.Example) > 0 {
+		buf.WriteString("== Examples\n\n")
+		buf.WriteString(fmt.Sprintf("[source,shell]\n----\n%s\n----\n\n", cmd.Example))
+	}
+
+	printOptionsAsciidoc(buf, cmd.NonInheritedFlags(), "Options")
+	printOptionsAsciidoc(buf, cmd.InheritedFlags(), "Options inherited from parent commands")
+
+	if hasSeeAlso(cmd, filter) || hasManHelpTopics(cmd) {
+		buf.WriteString("== SEE ALSO\n\n")
+		if cmd.HasParent() {
+			parent := cmd.Parent()
+			pname := parent.CommandPath()
+			ref = naming(parent)
+			buf.WriteString(fmt.Sprintf("* %s - %s\n", linkHandler(pname, ref), parent.Short))
+			cmd.VisitParents(func(c *cobra.Command) {
+				if c.DisableAutoGenTag {
+					cmd.DisableAutoGenTag = c.DisableAutoGenTag
+				}
+			})
+		}
+
+		children := cmd.Commands()
+		sort.Sort(byName(children))
+
+		for _, child := range children {
+			if !filter(child) {
+				continue
+			}
+			cname := name + " " + child.Name()
+			ref = naming(child)
+			buf.WriteString(fmt.Sprintf("* %s - %s\n", linkHandler(cname, ref), child.Short))
+		}
+		buf.WriteString("\n")
+	}
+	if !cmd.DisableAutoGenTag {
+		buf.WriteString("_Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006") + "_\n")
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// GenAsciidocTree will generate an AsciiDoc page for this command and all
+// descendants in the directory given. It returns an error if two commands
+// have the same file name, such as `cmd sub-third` and `cmd sub third`,
+// see GenAsciidocTreeFromOpts to name the files differently.
+func GenAsciidocTree(cmd *cobra.Command, dir string) error {
+	emptyStr := func(s string) string { return "" }
+	return GenAsciidocTreeCustom(cmd, dir, emptyStr, defaultAsciidocLinkHandler)
+}
+
+// GenAsciidocTreeCustom is the same as GenAsciidocTree, but
+// with custom filePrepender and linkHandler.
+func GenAsciidocTreeCustom(cmd *cobra.Command, dir string, filePrepender func(string) string, linkHandler func(string, string) string) error {
+	return genAsciidocTree(cmd, TreeOptions{Dir: dir}, filePrepender, linkHandler)
+}
+
+// GenAsciidocTreeFromOpts is the same as GenAsciidocTree, but
+// with the commands, the names of their pages and the output chosen by opts.
+func GenAsciidocTreeFromOpts(cmd *cobra.Command, opts TreeOptions) error {
+	emptyStr := func(s string) string { return "" }
+	return genAsciidocTree(cmd, opts, emptyStr, defaultAsciidocLinkHandler)
+}
+
+func genAsciidocTree(cmd *cobra.Command, opts TreeOptions, filePrepender func(string) string, linkHandler func(string, string) string) error {
+	opts = opts.withDefaults(defaultNaming, DefaultTreeFilter)
+	return genTree(cmd, opts, defaultNaming, DefaultTreeFilter, ".adoc", func(c *cobra.Command, filename string, w io.Writer) error {
+		if _, err := io.WriteString(w, filePrepender(filename)); err != nil {
+			return err
+		}
+		return genAsciidoc(c, w, opts.Naming, opts.Filter, linkHandler)
+	})
+}
+
+func printOptionsAsciidocMan(buf *bytes.Buffer, flags *pflag.FlagSet, title string) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	buf.WriteString("== " + title + "\n\n")
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Deprecated) > 0 || flag.Hidden {
+			return
+		}
+		varname, usage := pflag.UnquoteUsage(helpFlag(flag))
+		term := "*--" + flag.Name + "*"
+		if len(flag.Shorthand) > 0 && len(flag.ShorthandDeprecated) == 0 {
+			term = "*-" + flag.Shorthand + "*, " + term
+		}
+		if varname != "" {
+			term += "=_" + varname + "_"
+		}
+		if flag.DefValue != "" && flag.DefValue != "[]" {
+			usage += " (default " + asciidocLiteral(flag.DefValue) + ")"
+		}
+		buf.WriteString(fmt.Sprintf("%s::\n  %s\n\n", term, usage))
+	})
+}
+
+// printEntriesAsciidocMan writes the section title listing entries, their
+// names formatted with nameFormat.
+func printEntriesAsciidocMan(buf *bytes.Buffer, title string, entries []ManEntry, nameFormat string) {
+	if len(entries) == 0 {
+		return
+	}
+	buf.WriteString("== " + title + "\n\n")
+	for _, entry := range entries {
+		buf.WriteString(fmt.Sprintf(nameFormat+"::\n  %s\n\n", entry.Name, entry.Description))
+	}
+}
+
+// GenAsciidocMan creates an AsciiDoc page of the manpage doctype, which
+// Asciidoctor converts to a man page. The header argument may be nil.
+func GenAsciidocMan(cmd *cobra.Command, header *GenManHeader, w io.Writer) error {
+	if header == nil {
+		header = &GenManHeader{}
+	}
+	if err := fillHeader(header, cmd.CommandPath()); err != nil {
+		return err
+	}
+
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	// something like `rootcmd-subcmd1-subcmd2`
+	dashCommandName := strings.Replace(cmd.CommandPath(), " ", "-", -1)
+	description := cmd.Long
+	if len(description) == 0 {
+		description = cmd.Short
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("= %s(%s)\n", dashCommandName, manPageSection(header, cmd)))
+	buf.WriteString(":doctype: manpage\n")
+	buf.WriteString(fmt.Sprintf(":revdate: %s\n", header.date))
+	buf.WriteString(fmt.Sprintf(":manmanual: %s\n", header.Manual))
+	buf.WriteString(fmt.Sprintf(":mansource: %s\n\n", header.Source))
+
+	buf.WriteString("== NAME\n\n")
+	buf.WriteString(fmt.Sprintf("%s - %s\n\n", dashCommandName, cmd.Short))
+	if !isManHelpTopic(cmd) {
+		buf.WriteString("== SYNOPSIS\n\n")
+		buf.WriteString(fmt.Sprintf("*%s* %s\n\n", cmd.CommandPath(), strings.TrimPrefix(cmd.UseLine(), cmd.CommandPath()+" ")))
+	}
+	buf.WriteString("== DESCRIPTION\n\n")
+	buf.WriteString(description + "\n\n")
+
+	if !isManHelpTopic(cmd) {
+		printOptionsAsciidocMan(buf, cmd.NonInheritedFlags(), "OPTIONS")
+		printOptionsAsciidocMan(buf, cmd.InheritedFlags(), "OPTIONS INHERITED FROM PARENT COMMANDS")
+	}
+	sections := collectManSections(header, cmd)
+	printEntriesAsciidocMan(buf, "EXIT STATUS", sections.exitStatus, "*%s*")
+	printEntriesAsciidocMan(buf, "ENVIRONMENT", sections.environment, "*%s*")
+	printEntriesAsciidocMan(buf, "FILES", sections.files, "_%s_")
+
+	if len(cmd.Example) > 0 {
+		buf.WriteString("== EXAMPLE\n\n")
+		buf.WriteString(fmt.Sprintf("----\n%s\n----\n\n", cmd.Example))
+	}
+	if sections.bugs != "" {
+		buf.WriteString("== BUGS\n\n")
+		buf.WriteString(sections.bugs + "\n\n")
+	}
+	for _, section := range sections.custom {
+		buf.WriteString("== " + section.Name + "\n\n")
+		buf.WriteString(section.Content + "\n\n")
+	}
+	if hasSeeAlso(cmd, DefaultTreeFilter) || hasManHelpTopics(cmd) {
+		buf.WriteString("== SEE ALSO\n\n")
+		seealsos := make([]string, 0)
+		if cmd.HasParent() {
+			dashParentPath := strings.Replace(cmd.Parent().CommandPath(), " ", "-", -1)
+			seealsos = append(seealsos, fmt.Sprintf("*%s*(%s)", dashParentPath, header.Section))
+			cmd.VisitParents(func(c *cobra.Command) {
+				if c.DisableAutoGenTag {
+					cmd.DisableAutoGenTag = c.DisableAutoGenTag
+				}
+			})
+		}
+		children := cmd.Commands()
+		sort.Sort(byName(children))
+		for _, c := range children {
+			if (!c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand()) && !isManHelpTopic(c) {
+				continue
+			}
+			seealsos = append(seealsos, fmt.Sprintf("*%s-%s*(%s)", dashCommandName, c.Name(), manPageSection(header, c)))
+		}
+		buf.WriteString(strings.Join(seealsos, ", ") + "\n\n")
+	}
+	if !cmd.DisableAutoGenTag {
+		buf.WriteString(fmt.Sprintf("== HISTORY\n\n%s Auto generated by spf13/cobra\n", header.Date.Format("2-Jan-2006")))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// GenAsciidocManTree will generate an AsciiDoc page of the manpage doctype for
+// this command and all descendants in the directory given. The header may be nil.
+// The pages are named like the man pages GenManTree generates, with an
+// additional ".adoc" extension (e.g., "root-sub.1.adoc").
+func GenAsciidocManTree(cmd *cobra.Command, header *GenManHeader, dir string) error {
+	return GenAsciidocManTreeFromOpts(cmd, GenManTreeOptions{
+		Header:           header,
+		Path:             dir,
+		CommandSeparator: "-",
+	})
+}
+
+// GenAsciidocManTreeFromOpts is the same as GenManTreeFromOpts, but
+// generates AsciiDoc pages of the manpage doctype, named like the man pages
+// with an additional ".adoc" extension.
+func GenAsciidocManTreeFromOpts(cmd *cobra.Command, opts GenManTreeOptions) error {
+	return genManTree(cmd, opts, ".adoc", GenAsciidocMan)
+}
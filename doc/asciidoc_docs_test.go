@@ -0,0 +1,99 @@
+package doc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenAsciidocDoc(t *testing.T) {
+	// We generate on a subcommand so we have both subcommands and parents
+	buf := new(bytes.Buffer)
+	if err := GenAsciidoc(echoCmd, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "= root echo\n")
+	checkStringContains(t, output, echoCmd.Long)
+	checkStringContains(t, output, "[source,shell]\n----\n"+echoCmd.Example+"\n----")
+	checkStringContains(t, output, "|`+-b+`, `+--boolone+`\n|`+true+`\n|help message for flag boolone")
+	checkStringContains(t, output, "|`+-i+`, `+--intone int+`\n|`+123+`\n|help message for flag intone")
+	checkStringContains(t, output, "== Options inherited from parent commands")
+	checkStringContains(t, output, "rootflag")
+	checkStringContains(t, output, "* xref:root.adoc[root] - "+rootCmd.Short)
+	checkStringContains(t, output, "* xref:root_echo_echosub.adoc[root echo echosub] - "+echoSubCmd.Short)
+	checkStringOmits(t, output, deprecatedCmd.Short)
+}
+
+func TestGenAsciidocCustomLinks(t *testing.T) {
+	linkHandler := func(name, ref string) string {
+		return "<<" + ref + "," + name + ">>"
+	}
+	buf := new(bytes.Buffer)
+	if err := GenAsciidocCustom(echoCmd, buf, linkHandler); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "* <<root_echo_times,root echo times>> - "+timesCmd.Short)
+}
+
+func TestGenAsciidocNoTag(t *testing.T) {
+	rootCmd.DisableAutoGenTag = true
+	defer func() { rootCmd.DisableAutoGenTag = false }()
+
+	buf := new(bytes.Buffer)
+	if err := GenAsciidoc(rootCmd, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, buf.String(), "Auto generated")
+}
+
+func TestGenAsciidocMan(t *testing.T) {
+	header := &GenManHeader{
+		Title:       "Project",
+		Section:     "2",
+		Manual:      "Project manual",
+		Environment: []ManEntry{{"ROOT_CONFIG", "the configuration file"}},
+	}
+	buf := new(bytes.Buffer)
+	if err := GenAsciidocMan(echoCmd, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "= root-echo(2)\n:doctype: manpage\n")
+	checkStringContains(t, output, ":manmanual: Project manual\n")
+	checkStringContains(t, output, "== NAME\n\nroot-echo - "+echoCmd.Short)
+	checkStringContains(t, output, "*root echo* [string to echo] [flags]")
+	checkStringContains(t, output, "*-i*, *--intone*=_int_::\n  help message for flag intone (default `+123+`)")
+	checkStringContains(t, output, "== ENVIRONMENT\n\n*ROOT_CONFIG*::\n  the configuration file\n")
+	checkStringContains(t, output, "*root*(2), *root-echo-echosub*(2), *root-echo-times*(2)")
+}
+
+func TestGenAsciidocTree(t *testing.T) {
+	c := &cobra.Command{Use: "do [OPTIONS] arg1 arg2"}
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-asciidoc-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %s", err.Error())
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenAsciidocTree(c, tmpdir); err != nil {
+		t.Fatalf("GenAsciidocTree failed: %s", err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(tmpdir, "do.adoc")); err != nil {
+		t.Fatalf("Expected file 'do.adoc' to exist")
+	}
+
+	if err := GenAsciidocManTree(c, nil, tmpdir); err != nil {
+		t.Fatalf("GenAsciidocManTree failed: %s", err.Error())
+	}
+	if _, err := os.Stat(filepath.Join(tmpdir, "do.1.adoc")); err != nil {
+		t.Fatalf("Expected file 'do.1.adoc' to exist")
+	}
+}
@@ -0,0 +1,358 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// CompatChange is a difference between two versions of a command tree.
+type CompatChange struct {
+	// Command is the path of the command in the old tree.
+	Command string
+	// Flag is the name of the flag the change is about, empty if the change
+	// is about the command.
+	Flag string
+	// Breaking reports whether the change may break the scripts using the
+	// old version.
+	Breaking bool
+	// Message describes the change (e.g., "became required").
+	Message string
+}
+
+// String returns the change, e.g. `breaking: flag --count of "app sub" became required`.
+func (c CompatChange) String() string {
+	kind := "compatible"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	if c.Flag != "" {
+		return fmt.Sprintf("%s: flag --%s of %q %s", kind, c.Flag, c.Command, c.Message)
+	}
+	return fmt.Sprintf("%s: command %q %s", kind, c.Command, c.Message)
+}
+
+// CompatReport lists the changes between two versions of a command tree.
+type CompatReport struct {
+	Changes []CompatChange
+}
+
+// HasBreakingChanges reports whether a change may break the scripts using
+// the old version.
+func (r *CompatReport) HasBreakingChanges() bool {
+	for _, c := range r.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the changes, one per line, the breaking changes first.
+func (r *CompatReport) String() string {
+	buf := new(bytes.Buffer)
+	for _, breaking := range []bool{true, false} {
+		for _, c := range r.Changes {
+			if c.Breaking == breaking {
+				buf.WriteString(c.String() + "\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+// ReadJSONDoc reads a document written by GenJSON.
+func ReadJSONDoc(r io.Reader) (*JSONDoc, error) {
+	doc := &JSONDoc{}
+	if err := json.NewDecoder(r).Decode(doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != JSONDocVersion {
+		return nil, fmt.Errorf("unsupported version %d of the JSON documentation, expected %d", doc.Version, JSONDocVersion)
+	}
+	if doc.Command == nil {
+		return nil, fmt.Errorf("no command in the JSON documentation")
+	}
+	return doc, nil
+}
+
+// CompareCommands compares the command tree old, such as a snapshot read with
+// ReadJSONDoc from the output of GenJSON for a previous release, with the
+// tree of cmd.
+func CompareCommands(old *JSONDoc, cmd *cobra.Command) *CompatReport {
+	return CompareJSONDocs(old, &JSONDoc{Version: JSONDocVersion, Command: genJSONCommand(cmd, true)})
+}
+
+// CompareJSONDocs compares two versions of a command tree, as written by
+// GenJSON. The breaking changes are the removed or renamed commands, aliases
+// and flags, the commands which are no longer runnable, the changed or
+// removed flag shorthands, the changed flag types and defaults, the flags
+// which became required, the removed flag values and the stricter
+// positional arguments. A command renamed with its old name as an alias is
+// not removed. A removed command is reported as probably renamed when a
+// single added sibling has the same short description.
+func CompareJSONDocs(old, current *JSONDoc) *CompatReport {
+	report := &CompatReport{}
+	compareCommands(report, old.Command, current.Command, nil)
+	return report
+}
+
+// compareCommands compares old and current, whose parent in the current tree
+// is parent, nil for the root.
+func compareCommands(report *CompatReport, old, current, parent *JSONCommand) {
+	change := func(breaking bool, format string, a ...interface{}) {
+		report.Changes = append(report.Changes, CompatChange{Command: old.Path, Breaking: breaking, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if old.Runnable && !current.Runnable {
+		change(true, "is no longer runnable")
+	}
+	if !old.Hidden && current.Hidden {
+		change(false, "became hidden")
+	}
+	if old.Deprecated == "" && current.Deprecated != "" {
+		change(false, "became deprecated: %s", current.Deprecated)
+	}
+	for _, alias := range old.Aliases {
+		if !containsString(current.Aliases, alias) {
+			change(true, "lost its alias %q", alias)
+		}
+	}
+	for _, alias := range current.Aliases {
+		if !containsString(old.Aliases, alias) {
+			change(false, "has the new alias %q", alias)
+		}
+	}
+	if old.Runnable && current.Runnable {
+		compareArgs(change, old.Args, current.Args)
+	}
+	compareFlags(report, old, current, parent)
+
+	// The subcommands are found by name, then by alias if they were renamed
+	matched := make(map[*JSONCommand]bool)
+	for _, oldSub := range old.Subcommands {
+		newSub := findJSONSubcommand(current.Subcommands, oldSub.Name)
+		if newSub == nil {
+			if renamed := findRenamedJSONCommand(current.Subcommands, old.Subcommands, oldSub); renamed != nil {
+				report.Changes = append(report.Changes, CompatChange{Command: oldSub.Path, Breaking: true, Message: fmt.Sprintf("was removed, probably renamed to %q", renamed.Path)})
+				matched[renamed] = true
+				continue
+			}
+			report.Changes = append(report.Changes, CompatChange{Command: oldSub.Path, Breaking: true, Message: "was removed"})
+			continue
+		}
+		if newSub.Name != oldSub.Name {
+			report.Changes = append(report.Changes, CompatChange{Command: oldSub.Path, Message: fmt.Sprintf("was renamed to %q, with its old name as an alias", newSub.Path)})
+		}
+		matched[newSub] = true
+		compareCommands(report, oldSub, newSub, current)
+	}
+	for _, newSub := range current.Subcommands {
+		if !matched[newSub] {
+			report.Changes = append(report.Changes, CompatChange{Command: newSub.Path, Message: "was added"})
+		}
+	}
+}
+
+// findJSONSubcommand returns the command of commands named or aliased name.
+func findJSONSubcommand(commands []*JSONCommand, name string) *JSONCommand {
+	for _, c := range commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	for _, c := range commands {
+		if containsString(c.Aliases, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// findRenamedJSONCommand returns the only command of commands which is not
+// in oldCommands and has the same non-empty short description as old, which
+// is probably old renamed.
+func findRenamedJSONCommand(commands, oldCommands []*JSONCommand, old *JSONCommand) *JSONCommand {
+	if old.Short == "" {
+		return nil
+	}
+	var renamed *JSONCommand
+	for _, c := range commands {
+		if c.Short == old.Short && findJSONSubcommand(oldCommands, c.Name) == nil {
+			if renamed != nil {
+				// The command cannot be told apart
+				return nil
+			}
+			renamed = c
+		}
+	}
+	return renamed
+}
+
+func compareArgs(change func(bool, string, ...interface{}), old, current *JSONArgs) {
+	if old == nil {
+		old = &JSONArgs{}
+	}
+	if current == nil {
+		current = &JSONArgs{}
+	}
+	if old.Unknown || current.Unknown {
+		// The arguments accepted by one of the commands are not known
+		return
+	}
+	if current.Min > old.Min {
+		change(true, "requires at least %d arguments instead of %d", current.Min, old.Min)
+	} else if current.Min < old.Min {
+		change(false, "requires at least %d arguments instead of %d", current.Min, old.Min)
+	}
+	switch {
+	case current.Max != nil && old.Max == nil:
+		change(true, "accepts at most %d arguments instead of any number", *current.Max)
+	case current.Max != nil && *current.Max < *old.Max:
+		change(true, "accepts at most %d arguments instead of %d", *current.Max, *old.Max)
+	case old.Max != nil && current.Max == nil:
+		change(false, "accepts any number of arguments instead of at most %d", *old.Max)
+	case old.Max != nil && *current.Max > *old.Max:
+		change(false, "accepts at most %d arguments instead of %d", *current.Max, *old.Max)
+	}
+	if current.OnlyValidArgs {
+		if !old.OnlyValidArgs {
+			change(true, "only accepts its valid arguments")
+		} else {
+			for _, v := range old.ValidArgs {
+				if !containsJSONValue(current.ValidArgs, v.Value) {
+					change(true, "no longer accepts the argument %q", v.Value)
+				}
+			}
+		}
+	}
+}
+
+// jsonFlags returns the flags a command accepts, local and inherited, by
+// name, and the names of its local flags.
+func jsonFlags(c *JSONCommand) (map[string]JSONFlag, map[string]bool) {
+	flags := make(map[string]JSONFlag)
+	local := make(map[string]bool)
+	if c == nil {
+		return flags, local
+	}
+	for _, f := range c.InheritedOptions {
+		flags[f.Name] = f
+	}
+	for _, f := range c.Options {
+		flags[f.Name] = f
+		local[f.Name] = true
+	}
+	return flags, local
+}
+
+// compareFlags compares the flags of oldCmd and newCmd, whose parent in the
+// current tree is parent. The changes of the inherited flags are reported
+// once, on the command defining them, unless a command no longer inherits a
+// flag its parent still accepts.
+func compareFlags(report *CompatReport, oldCmd, newCmd, parent *JSONCommand) {
+	oldFlags, oldLocal := jsonFlags(oldCmd)
+	newFlags, newLocal := jsonFlags(newCmd)
+	parentFlags, _ := jsonFlags(parent)
+	var names []string
+	for name := range oldFlags {
+		names = append(names, name)
+	}
+	for name := range newFlags {
+		if _, ok := oldFlags[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		change := func(breaking bool, format string, a ...interface{}) {
+			report.Changes = append(report.Changes, CompatChange{Command: oldCmd.Path, Flag: name, Breaking: breaking, Message: fmt.Sprintf(format, a...)})
+		}
+		old, inOld := oldFlags[name]
+		current, inNew := newFlags[name]
+		if !oldLocal[name] && !newLocal[name] {
+			if _, inParent := parentFlags[name]; inOld && !inNew && inParent {
+				change(true, "was removed")
+			}
+			continue
+		}
+		switch {
+		case !inNew:
+			change(true, "was removed")
+			continue
+		case !inOld && current.Required:
+			change(true, "was added as a required flag")
+			continue
+		case !inOld:
+			change(false, "was added")
+			continue
+		}
+
+		if old.Shorthand != "" && current.Shorthand != old.Shorthand {
+			if current.Shorthand == "" {
+				change(true, "lost its shorthand -%s", old.Shorthand)
+			} else {
+				change(true, "has the shorthand -%s instead of -%s", current.Shorthand, old.Shorthand)
+			}
+		} else if old.Shorthand == "" && current.Shorthand != "" {
+			change(false, "has the new shorthand -%s", current.Shorthand)
+		}
+		if current.Type != old.Type {
+			change(true, "has the type %s instead of %s", current.Type, old.Type)
+		}
+		if current.DefaultValue != old.DefaultValue {
+			change(true, "defaults to %q instead of %q", current.DefaultValue, old.DefaultValue)
+		}
+		if old.NoOptDefaultValue != "" && current.NoOptDefaultValue == "" {
+			change(true, "requires a value")
+		}
+		if !old.Required && current.Required {
+			change(true, "became required")
+		}
+		if old.Deprecated == "" && current.Deprecated != "" {
+			change(false, "became deprecated: %s", current.Deprecated)
+		}
+		if len(current.AllowedValues) > 0 {
+			for _, v := range old.AllowedValues {
+				if !containsJSONValue(current.AllowedValues, v.Value) {
+					change(true, "no longer accepts the value %q", v.Value)
+				}
+			}
+			if len(old.AllowedValues) == 0 {
+				change(true, "only accepts the values %s", formatJSONValues(current.AllowedValues))
+			}
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsJSONValue(values []JSONValue, value string) bool {
+	for _, v := range values {
+		if v.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func formatJSONValues(values []JSONValue) string {
+	var s []string
+	for _, v := range values {
+		s = append(s, fmt.Sprintf("%q", v.Value))
+	}
+	return strings.Join(s, ", ")
+}
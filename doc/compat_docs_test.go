@@ -0,0 +1,180 @@
+package doc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// compatCmd returns a command tree, changed by change.
+func compatCmd(change func(root, get, set *cobra.Command)) *cobra.Command {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.PersistentFlags().StringP("output", "o", "text", "output format")
+	get := &cobra.Command{Use: "get", Short: "Get a resource", Aliases: []string{"g"}, Args: cobra.RangeArgs(1, 2), Run: emptyRun}
+	get.Flags().IntP("limit", "l", 10, "limit")
+	get.Flags().Bool("watch", false, "watch")
+	set := &cobra.Command{Use: "set", Short: "Set a resource", Run: emptyRun}
+	root.AddCommand(get, set)
+	if change != nil {
+		change(root, get, set)
+	}
+	return root
+}
+
+// compatTree returns the documentation of compatCmd(change), as read from GenJSON.
+func compatTree(t *testing.T, change func(root, get, set *cobra.Command)) *JSONDoc {
+	buf := new(bytes.Buffer)
+	if err := GenJSON(compatCmd(change), buf); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ReadJSONDoc(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestCompareJSONDocs(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(root, get, set *cobra.Command)
+		expected []string
+	}{
+		{
+			name: "removed command and alias",
+			change: func(root, get, set *cobra.Command) {
+				root.RemoveCommand(set)
+				get.Aliases = nil
+			},
+			expected: []string{
+				`breaking: command "app get" lost its alias "g"`,
+				`breaking: command "app set" was removed`,
+			},
+		},
+		{
+			name: "renamed commands",
+			change: func(root, get, set *cobra.Command) {
+				get.Use, get.Aliases = "fetch", []string{"g", "get"}
+				set.Use = "put"
+			},
+			expected: []string{
+				`compatible: command "app get" was renamed to "app fetch", with its old name as an alias`,
+				`compatible: command "app get" has the new alias "get"`,
+				`breaking: command "app set" was removed, probably renamed to "app put"`,
+			},
+		},
+		{
+			name: "ambiguous rename",
+			change: func(root, get, set *cobra.Command) {
+				set.Use = "put"
+				root.AddCommand(&cobra.Command{Use: "assign", Short: set.Short, Run: emptyRun})
+			},
+			expected: []string{
+				`breaking: command "app set" was removed`,
+				`compatible: command "app assign" was added`,
+				`compatible: command "app put" was added`,
+			},
+		},
+		{
+			name: "flags",
+			change: func(root, get, set *cobra.Command) {
+				root.PersistentFlags().Lookup("output").DefValue = "json"
+				get.Flags().Lookup("limit").Shorthand = "n"
+				get.Flags().MarkHidden("watch")
+				cobra.MarkFlagRequired(get.Flags(), "watch")
+				set.Flags().String("name", "", "name")
+				cobra.MarkFlagRequired(set.Flags(), "name")
+				set.Flags().Bool("force", false, "force")
+			},
+			expected: []string{
+				`breaking: flag --output of "app" defaults to "json" instead of "text"`,
+				`breaking: flag --limit of "app get" has the shorthand -n instead of -l`,
+				`breaking: flag --watch of "app get" became required`,
+				`compatible: flag --force of "app set" was added`,
+				`breaking: flag --name of "app set" was added as a required flag`,
+			},
+		},
+		{
+			name: "persistent flag made local",
+			change: func(root, get, set *cobra.Command) {
+				output := root.PersistentFlags().Lookup("output")
+				root.ResetFlags()
+				root.Flags().AddFlag(output)
+			},
+			expected: []string{
+				`breaking: flag --output of "app get" was removed`,
+				`breaking: flag --output of "app help" was removed`,
+				`breaking: flag --output of "app set" was removed`,
+			},
+		},
+		{
+			name: "args",
+			change: func(root, get, set *cobra.Command) {
+				get.Args = cobra.ExactArgs(2)
+				set.Args = cobra.MaximumNArgs(1)
+			},
+			expected: []string{
+				`breaking: command "app get" requires at least 2 arguments instead of 1`,
+				`breaking: command "app set" accepts at most 1 arguments instead of any number`,
+			},
+		},
+		{
+			name: "unknown args",
+			change: func(root, get, set *cobra.Command) {
+				get.Args = func(cmd *cobra.Command, args []string) error { return nil }
+			},
+		},
+		{
+			name: "compatible changes",
+			change: func(root, get, set *cobra.Command) {
+				get.Args = cobra.MinimumNArgs(0)
+				set.Deprecated = "use get"
+				root.AddCommand(&cobra.Command{Use: "delete", Run: emptyRun})
+			},
+			expected: []string{
+				`compatible: command "app get" requires at least 0 arguments instead of 1`,
+				`compatible: command "app get" accepts any number of arguments instead of at most 2`,
+				`compatible: command "app set" became deprecated: use get`,
+				`compatible: command "app delete" was added`,
+			},
+		},
+	}
+
+	old := compatTree(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := CompareJSONDocs(old, compatTree(t, tt.change))
+			var got []string
+			for _, c := range report.Changes {
+				got = append(got, c.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected changes:\n%s\nGot:\n%s", strings.Join(tt.expected, "\n"), strings.Join(got, "\n"))
+			}
+			breaking := false
+			for _, e := range tt.expected {
+				breaking = breaking || strings.HasPrefix(e, "breaking")
+			}
+			if report.HasBreakingChanges() != breaking {
+				t.Errorf("Expected HasBreakingChanges to be %v", breaking)
+			}
+		})
+	}
+}
+
+func TestCompareCommandsUnchanged(t *testing.T) {
+	report := CompareCommands(compatTree(t, nil), compatCmd(nil))
+	if len(report.Changes) != 0 {
+		t.Errorf("Expected no change, got:\n%s", report)
+	}
+}
+
+func TestReadJSONDocVersion(t *testing.T) {
+	_, err := ReadJSONDoc(strings.NewReader(`{"version": 2, "command": {"name": "app", "path": "app"}}`))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), "unsupported version 2")
+}
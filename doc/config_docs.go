@@ -0,0 +1,515 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// configSection is the section of the configuration of a command: the keys
+// of its flags, then the sections of its subcommands, nested by command path.
+type configSection struct {
+	cmd      *cobra.Command
+	flags    []*pflag.Flag
+	sections []*configSection
+}
+
+// genConfigSection returns the section of cmd, holding its local and
+// persistent flags. The hidden flags and the help flag are not configured,
+// but the deprecated flags, which pflag hides, are, so that the existing
+// files remain valid. The subcommands without any key are left out. It
+// returns an error if a flag and a subcommand have the same key.
+func genConfigSection(cmd *cobra.Command) (*configSection, error) {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	section := &configSection{cmd: cmd}
+	keys := make(map[string]bool)
+	cmd.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if (flag.Hidden && flag.Deprecated == "") || flag.Name == "help" {
+			return
+		}
+		section.flags = append(section.flags, flag)
+		keys[flag.Name] = true
+	})
+
+	children := cmd.Commands()
+	sort.Sort(byName(children))
+	for _, c := range children {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		sub, err := genConfigSection(c)
+		if err != nil {
+			return nil, err
+		}
+		if len(sub.flags) == 0 && len(sub.sections) == 0 {
+			continue
+		}
+		if keys[c.Name()] {
+			return nil, fmt.Errorf("flag --%s and command %q have the same key in the configuration of %q", c.Name(), c.CommandPath(), cmd.CommandPath())
+		}
+		section.sections = append(section.sections, sub)
+	}
+	return section, nil
+}
+
+// configTypes are the JSON types of the values of the pflag types. The
+// values of the slices are arrays, and those of the maps are objects.
+var configTypes = map[string]string{
+	"bool":        "boolean",
+	"count":       "integer",
+	"int":         "integer",
+	"int8":        "integer",
+	"int16":       "integer",
+	"int32":       "integer",
+	"int64":       "integer",
+	"uint":        "integer",
+	"uint8":       "integer",
+	"uint16":      "integer",
+	"uint32":      "integer",
+	"uint64":      "integer",
+	"float32":     "number",
+	"float64":     "number",
+	"string":      "string",
+	"duration":    "string",
+	"ip":          "string",
+	"ipMask":      "string",
+	"ipNet":       "string",
+	"bytesHex":    "string",
+	"bytesBase64": "string",
+
+	"boolSlice":     "boolean",
+	"durationSlice": "string",
+	"intSlice":      "integer",
+	"int32Slice":    "integer",
+	"int64Slice":    "integer",
+	"uintSlice":     "integer",
+	"float32Slice":  "number",
+	"float64Slice":  "number",
+	"ipSlice":       "string",
+	"stringSlice":   "string",
+	"stringArray":   "string",
+
+	"stringToInt":    "integer",
+	"stringToInt64":  "integer",
+	"stringToString": "string",
+}
+
+// configType returns the JSON type of the value of flag, "array" or
+// "object" with the type of their items, or "" if the type of flag is
+// unknown.
+func configType(flag *pflag.Flag) (string, string) {
+	flagType := flag.Value.Type()
+	typ, ok := configTypes[flagType]
+	switch {
+	case !ok:
+		return "", ""
+	case strings.HasSuffix(flagType, "Slice") || strings.HasSuffix(flagType, "Array"):
+		return "array", typ
+	case strings.HasPrefix(flagType, "stringTo"):
+		return "object", typ
+	}
+	return typ, ""
+}
+
+// configValue returns s, a value of the JSON type typ. It returns false if s
+// is not a value of typ.
+func configValue(typ, s string) (interface{}, bool) {
+	switch typ {
+	case "boolean":
+		v, err := strconv.ParseBool(s)
+		return v, err == nil
+	case "integer":
+		v, err := strconv.ParseInt(s, 0, 64)
+		return v, err == nil
+	case "number":
+		v, err := strconv.ParseFloat(s, 64)
+		return v, err == nil
+	}
+	return s, true
+}
+
+// configDefault returns the default value of flag, with the JSON type of
+// configType. It returns false if the default value is not known, such as
+// for the flags of an unknown type.
+func configDefault(flag *pflag.Flag) (interface{}, bool) {
+	typ, itemType := configType(flag)
+	switch typ {
+	case "":
+		return nil, false
+	case "array", "object":
+		// The slices and maps are written as "[a,b]" and "[k=v]"
+		s := strings.TrimSuffix(strings.TrimPrefix(flag.DefValue, "["), "]")
+		var records []string
+		if s != "" {
+			var err error
+			if records, err = csv.NewReader(strings.NewReader(s)).Read(); err != nil {
+				return nil, false
+			}
+		}
+		if typ == "array" {
+			items := make([]interface{}, 0, len(records))
+			for _, record := range records {
+				item, ok := configValue(itemType, record)
+				if !ok {
+					return nil, false
+				}
+				items = append(items, item)
+			}
+			return items, true
+		}
+		entries := make(map[string]interface{}, len(records))
+		for _, record := range records {
+			entry := strings.SplitN(record, "=", 2)
+			if len(entry) != 2 {
+				return nil, false
+			}
+			value, ok := configValue(itemType, entry[1])
+			if !ok {
+				return nil, false
+			}
+			entries[entry[0]] = value
+		}
+		return entries, true
+	}
+	return configValue(typ, flag.DefValue)
+}
+
+// configEnum returns the values of a flag marked with cobra.MarkFlagEnum,
+// with the JSON type of its value, or of its items for a slice.
+func configEnum(flag *pflag.Flag) []interface{} {
+	typ, itemType := configType(flag)
+	if itemType != "" {
+		typ = itemType
+	}
+	var enum []interface{}
+	for _, v := range flag.Annotations[cobra.FlagEnumValues] {
+		value := genJSONValue(v).Value
+		if typed, ok := configValue(typ, value); ok {
+			enum = append(enum, typed)
+		} else {
+			enum = append(enum, value)
+		}
+	}
+	return enum
+}
+
+// configUsage returns usage, the usage of flag, followed by the deprecation
+// message of flag if it is deprecated.
+func configUsage(flag *pflag.Flag, usage string) string {
+	if flag.Deprecated != "" {
+		return fmt.Sprintf("%s (deprecated: %s)", usage, flag.Deprecated)
+	}
+	return usage
+}
+
+// ConfigSchema is a JSON Schema of the configuration file of a command, or
+// of one of its keys.
+type ConfigSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is the JSON type of the key, empty if any value is accepted.
+	Type  string        `json:"type,omitempty"`
+	Items *ConfigSchema `json:"items,omitempty"`
+	Enum  []interface{} `json:"enum,omitempty"`
+	// Default is the default value of the key, nil if it is not known.
+	Default    interface{}              `json:"default,omitempty"`
+	Properties map[string]*ConfigSchema `json:"properties,omitempty"`
+	// AdditionalProperties is false for the objects of the commands, which
+	// only accept the keys of their flags and subcommands, and is the
+	// schema of the values of the maps.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+}
+
+// NewConfigSchema returns the JSON Schema of the configuration file of cmd,
+// such as the $HOME/.app.yaml file read by the applications created by
+// `cobra init`. The keys of the file are the local and persistent flags of
+// cmd, and the objects of its subcommands, named like them, which hold their
+// own flags and subcommands. The schema can be changed before it is written,
+// for instance to accept other keys.
+func NewConfigSchema(cmd *cobra.Command) (*ConfigSchema, error) {
+	section, err := genConfigSection(cmd)
+	if err != nil {
+		return nil, err
+	}
+	schema := genConfigSchema(section)
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = "Configuration of " + cmd.CommandPath()
+	return schema, nil
+}
+
+// GenConfigSchema writes the JSON Schema of the configuration file of cmd,
+// as returned by NewConfigSchema.
+func GenConfigSchema(cmd *cobra.Command, w io.Writer) error {
+	schema, err := NewConfigSchema(cmd)
+	if err != nil {
+		return err
+	}
+	final, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(final, '\n'))
+	return err
+}
+
+func genConfigSchema(section *configSection) *ConfigSchema {
+	schema := &ConfigSchema{
+		Description:          section.cmd.Short,
+		Type:                 "object",
+		Properties:           make(map[string]*ConfigSchema),
+		AdditionalProperties: false,
+	}
+	for _, flag := range section.flags {
+		schema.Properties[flag.Name] = genConfigFlagSchema(flag)
+	}
+	for _, sub := range section.sections {
+		schema.Properties[sub.cmd.Name()] = genConfigSchema(sub)
+	}
+	return schema
+}
+
+func genConfigFlagSchema(flag *pflag.Flag) *ConfigSchema {
+	typ, itemType := configType(flag)
+	schema := &ConfigSchema{Description: configUsage(flag, flag.Usage), Type: typ}
+	if def, ok := configDefault(flag); ok {
+		schema.Default = def
+	}
+	enum := configEnum(flag)
+	switch typ {
+	case "array":
+		schema.Items = &ConfigSchema{Type: itemType, Enum: enum}
+	case "object":
+		schema.AdditionalProperties = &ConfigSchema{Type: itemType}
+	default:
+		schema.Enum = enum
+	}
+	return schema
+}
+
+// configSampleValue returns the default value of flag, or its default as a
+// string if it is not known.
+func configSampleValue(flag *pflag.Flag) interface{} {
+	if def, ok := configDefault(flag); ok {
+		return def
+	}
+	return flag.DefValue
+}
+
+// configComment writes text as comment lines, indented by indent.
+func configComment(buf *bytes.Buffer, indent, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		buf.WriteString(strings.TrimRight(indent+"# "+line, " ") + "\n")
+	}
+}
+
+// configSampleHeader writes the comment starting a sample configuration of cmd.
+func configSampleHeader(buf *bytes.Buffer, cmd *cobra.Command) {
+	configComment(buf, "", "Configuration of "+cmd.CommandPath())
+	configComment(buf, "", cmd.Short)
+}
+
+// GenConfigYaml writes a sample YAML configuration file of cmd, such as the
+// $HOME/.app.yaml file read by the applications created by `cobra init`. Its
+// keys, described by NewConfigSchema, are set to the default values of their
+// flags, and preceded by the usage of the flags as comments.
+func GenConfigYaml(cmd *cobra.Command, w io.Writer) error {
+	section, err := genConfigSection(cmd)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	configSampleHeader(buf, cmd)
+	if err := genConfigYaml(buf, section, ""); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// genConfigYaml writes the keys of section, each after a blank line and
+// indented by indent.
+func genConfigYaml(buf *bytes.Buffer, section *configSection, indent string) error {
+	first := indent != ""
+	separate := func() {
+		if !first {
+			buf.WriteString("\n")
+		}
+		first = false
+	}
+	for _, flag := range section.flags {
+		key, err := yamlConfigValue(flag.Name)
+		if err != nil {
+			return err
+		}
+		value, err := yamlConfigValue(configSampleValue(flag))
+		if err != nil {
+			return err
+		}
+		separate()
+		configComment(buf, indent, configUsage(flag, cobra.EnumFlagUsage(flag)))
+		buf.WriteString(indent + key + ": " + value + "\n")
+	}
+	for _, sub := range section.sections {
+		key, err := yamlConfigValue(sub.cmd.Name())
+		if err != nil {
+			return err
+		}
+		separate()
+		configComment(buf, indent, sub.cmd.Short)
+		buf.WriteString(indent + key + ":\n")
+		if err := genConfigYaml(buf, sub, indent+"  "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlConfigValue returns v on a single line. The arrays and objects, and
+// the strings YAML would write on several lines, are written as JSON, which
+// is YAML in flow style.
+func yamlConfigValue(v interface{}) (string, error) {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		return jsonConfigValue(v)
+	}
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(value, "\n") {
+		return jsonConfigValue(v)
+	}
+	return value, nil
+}
+
+func jsonConfigValue(v interface{}) (string, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// GenConfigToml is the same as GenConfigYaml, but writes a TOML
+// configuration file, in which each subcommand is a table.
+func GenConfigToml(cmd *cobra.Command, w io.Writer) error {
+	section, err := genConfigSection(cmd)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	configSampleHeader(buf, cmd)
+	if err := genConfigToml(buf, section, ""); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// genConfigToml writes the keys of section, then the tables of its
+// subcommands, whose names are prefixed with table, the name of the table of
+// section.
+func genConfigToml(buf *bytes.Buffer, section *configSection, table string) error {
+	for _, flag := range section.flags {
+		value, err := tomlConfigValue(configSampleValue(flag))
+		if err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		configComment(buf, "", configUsage(flag, cobra.EnumFlagUsage(flag)))
+		buf.WriteString(tomlConfigKey(flag.Name) + " = " + value + "\n")
+	}
+	for _, sub := range section.sections {
+		name := tomlConfigKey(sub.cmd.Name())
+		if table != "" {
+			name = table + "." + name
+		}
+		buf.WriteString("\n")
+		configComment(buf, "", sub.cmd.Short)
+		buf.WriteString("[" + name + "]\n")
+		if err := genConfigToml(buf, sub, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tomlConfigKey returns key, quoted if it is not a bare key.
+func tomlConfigKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			value, _ := jsonConfigValue(key)
+			return value
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+// tomlConfigValue returns v, a value of configDefault, as a TOML value. The
+// strings are written as JSON strings, which are TOML basic strings.
+func tomlConfigValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		value := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(value, ".") {
+			// TOML floats have a fractional part
+			value += ".0"
+		}
+		return value, nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			value, err := tomlConfigValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return "{}", nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		entries := make([]string, 0, len(keys))
+		for _, key := range keys {
+			value, err := tomlConfigValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, tomlConfigKey(key)+" = "+value)
+		}
+		return "{ " + strings.Join(entries, ", ") + " }", nil
+	}
+	return jsonConfigValue(v)
+}
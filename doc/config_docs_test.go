@@ -0,0 +1,210 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// configCmd returns a command tree with flags of most types.
+func configCmd() *cobra.Command {
+	root := &cobra.Command{Use: "app", Short: "An application", Run: emptyRun}
+	root.PersistentFlags().String("config", "", "config file")
+	root.PersistentFlags().CountP("verbose", "v", "verbosity")
+	root.Flags().Bool("local", true, "local flag")
+	root.Flags().String("secret", "", "hidden flag")
+	root.Flags().MarkHidden("secret")
+
+	serve := &cobra.Command{Use: "serve", Short: "Serve the application", Run: emptyRun}
+	serve.Flags().IntP("port", "p", 8080, "port to listen on")
+	serve.Flags().Float64("ratio", 0.5, "ratio")
+	serve.Flags().Duration("timeout", 30*time.Second, "timeout")
+	serve.Flags().StringSlice("hosts", []string{"a", "b,c"}, "hosts")
+	serve.Flags().IntSlice("codes", nil, "codes")
+	serve.Flags().StringToString("labels", map[string]string{"env": "dev"}, "labels")
+	serve.Flags().String("format", "text", "output format")
+	cobra.MarkFlagEnum(serve.Flags(), "format", "text", "json\tJSON lines")
+	serve.Flags().String("old", "", "old flag")
+	serve.Flags().MarkDeprecated("old", "use --format")
+
+	empty := &cobra.Command{Use: "version", Run: emptyRun}
+	hidden := &cobra.Command{Use: "debug", Hidden: true, Run: emptyRun}
+	hidden.Flags().Bool("trace", false, "trace")
+	root.AddCommand(serve, empty, hidden)
+	return root
+}
+
+func TestGenConfigSchema(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenConfigSchema(configCmd(), buf); err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	checkStringContains(t, buf.String(), `"$schema": "http://json-schema.org/draft-07/schema#"`)
+	if schema["title"] != "Configuration of app" || schema["additionalProperties"] != false {
+		t.Errorf("Unexpected root schema %v", schema)
+	}
+	root := schema["properties"].(map[string]interface{})
+	if keys := sortedKeys(root); !reflect.DeepEqual(keys, []string{"config", "local", "serve", "verbose"}) {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	serve := root["serve"].(map[string]interface{})
+	if serve["description"] != "Serve the application" || serve["additionalProperties"] != false {
+		t.Errorf("Unexpected serve schema %v", serve)
+	}
+
+	tests := []struct {
+		name     string
+		schema   interface{}
+		expected string
+	}{
+		{"config", root["config"], `{"default":"","description":"config file","type":"string"}`},
+		{"verbose", root["verbose"], `{"default":0,"description":"verbosity","type":"integer"}`},
+		{"local", root["local"], `{"default":true,"description":"local flag","type":"boolean"}`},
+		{"port", serve["properties"].(map[string]interface{})["port"], `{"default":8080,"description":"port to listen on","type":"integer"}`},
+		{"ratio", serve["properties"].(map[string]interface{})["ratio"], `{"default":0.5,"description":"ratio","type":"number"}`},
+		{"timeout", serve["properties"].(map[string]interface{})["timeout"], `{"default":"30s","description":"timeout","type":"string"}`},
+		{"hosts", serve["properties"].(map[string]interface{})["hosts"], `{"default":["a","b,c"],"description":"hosts","items":{"type":"string"},"type":"array"}`},
+		{"codes", serve["properties"].(map[string]interface{})["codes"], `{"default":[],"description":"codes","items":{"type":"integer"},"type":"array"}`},
+		{"labels", serve["properties"].(map[string]interface{})["labels"], `{"additionalProperties":{"type":"string"},"default":{"env":"dev"},"description":"labels","type":"object"}`},
+		{"format", serve["properties"].(map[string]interface{})["format"], `{"default":"text","description":"output format","enum":["text","json"],"type":"string"}`},
+		{"old", serve["properties"].(map[string]interface{})["old"], `{"default":"","description":"old flag (deprecated: use --format)","type":"string"}`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(tt.schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Expected the schema of %s to be %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestGenConfigSchemaKeyCollision(t *testing.T) {
+	root := &cobra.Command{Use: "app", Run: emptyRun}
+	root.Flags().String("serve", "", "serve")
+	serve := &cobra.Command{Use: "serve", Run: emptyRun}
+	serve.Flags().Int("port", 0, "port")
+	root.AddCommand(serve)
+
+	err := GenConfigSchema(root, new(bytes.Buffer))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), `flag --serve and command "app serve" have the same key`)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// configSampleCmd returns a command tree with flags on several levels.
+func configSampleCmd() *cobra.Command {
+	root := &cobra.Command{Use: "app", Short: "An application", Run: emptyRun}
+	root.PersistentFlags().String("config", "", "config file (default is $HOME/.app.yaml)")
+	serve := &cobra.Command{Use: "serve", Short: "Serve the application", Run: emptyRun}
+	serve.Flags().IntP("port", "p", 8080, "port to listen on")
+	serve.Flags().StringSlice("hosts", []string{"a", "b"}, "hosts\nto serve")
+	cobra.MarkFlagEnum(serve.Flags(), "hosts", "a", "b", "c")
+	tls := &cobra.Command{Use: "tls", Short: "Serve over TLS", Run: emptyRun}
+	tls.Flags().StringToString("certs", map[string]string{"a": "a.pem"}, "certificates")
+	tls.Flags().Float64("ratio", 1, "")
+	serve.AddCommand(tls)
+	root.AddCommand(serve)
+	return root
+}
+
+func TestGenConfigYaml(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenConfigYaml(configSampleCmd(), buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Configuration of app
+# An application
+
+# config file (default is $HOME/.app.yaml)
+config: ""
+
+# Serve the application
+serve:
+  # hosts
+  # to serve (one of: a, b, c)
+  hosts: ["a","b"]
+
+  # port to listen on
+  port: 8080
+
+  # Serve over TLS
+  tls:
+    # certificates
+    certs: {"a":"a.pem"}
+
+    ratio: 1
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	buf.Reset()
+	if err := GenConfigYaml(configCmd(), buf); err != nil {
+		t.Fatal(err)
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &config); err != nil {
+		t.Fatal(err)
+	}
+	serve := config["serve"].(map[interface{}]interface{})
+	if serve["timeout"] != "30s" || serve["ratio"] != 0.5 || serve["format"] != "text" || config["local"] != true {
+		t.Errorf("Unexpected configuration %v", config)
+	}
+}
+
+func TestGenConfigToml(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenConfigToml(configSampleCmd(), buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Configuration of app
+# An application
+
+# config file (default is $HOME/.app.yaml)
+config = ""
+
+# Serve the application
+[serve]
+
+# hosts
+# to serve (one of: a, b, c)
+hosts = ["a", "b"]
+
+# port to listen on
+port = 8080
+
+# Serve over TLS
+[serve.tls]
+
+# certificates
+certs = { a = "a.pem" }
+
+ratio = 1.0
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,301 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// diffContext is the number of unchanged lines around the changes of a diff.
+const diffContext = 3
+
+// The dates the documentation was generated on, which are replaced by
+// driftDate before comparing the files.
+var (
+	dateMonths = `(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)`
+	// headerDate is the "Jan 2006" date of the headers of man pages.
+	headerDate = regexp.MustCompile(dateMonths + ` \d{4}`)
+	// tagDate is the "2-Jan-2006" date of the "Auto generated by spf13/cobra"
+	// tags, whose dashes are escaped in man pages.
+	tagDate = regexp.MustCompile(`\d{1,2}(?:\\)?-` + dateMonths + `(?:\\)?-\d{4}`)
+	// mdocDate is the "January 2, 2006" date of mdoc pages.
+	mdocDate = regexp.MustCompile(`[A-Z][a-z]+ \d{1,2}, \d{4}`)
+)
+
+// driftDate replaces the dates in the compared lines.
+const driftDate = "<date>"
+
+// DocDrift is the difference between the documentation generated for a
+// command tree and the files of an output directory.
+type DocDrift struct {
+	// Added are the generated files missing from the directory.
+	Added []string
+	// Removed are the files of the directory that are no longer generated.
+	Removed []string
+	// Changed are the files whose generated content differs from the directory.
+	Changed []string
+	// Diffs are the unified diffs of the changed files from the content of
+	// the directory to the generated content, by file name.
+	Diffs map[string]string
+}
+
+// HasDrift reports whether the directory is not up to date.
+func (d *DocDrift) HasDrift() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// String returns a report of the drift.
+func (d *DocDrift) String() string {
+	buf := new(bytes.Buffer)
+	for _, name := range d.Added {
+		buf.WriteString(fmt.Sprintf("added: %s\n", name))
+	}
+	for _, name := range d.Removed {
+		buf.WriteString(fmt.Sprintf("removed: %s\n", name))
+	}
+	for _, name := range d.Changed {
+		buf.WriteString(fmt.Sprintf("changed: %s\n", name))
+	}
+	for _, name := range d.Changed {
+		buf.WriteString(d.Diffs[name])
+	}
+	return buf.String()
+}
+
+// DiffDocs generates in memory the documentation of this command and all
+// available descendants with gen, such as GenMarkdown, each in the file named
+// by filename, and compares it with the files of dir. See DiffDocFiles for
+// the files compared.
+func DiffDocs(cmd *cobra.Command, dir string, gen func(*cobra.Command, io.Writer) error, filename func(*cobra.Command) string) (*DocDrift, error) {
+	files := make(map[string][]byte)
+	var genFiles func(c *cobra.Command) error
+	genFiles = func(c *cobra.Command) error {
+		for _, child := range c.Commands() {
+			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
+				continue
+			}
+			if err := genFiles(child); err != nil {
+				return err
+			}
+		}
+		buf := new(bytes.Buffer)
+		if err := gen(c, buf); err != nil {
+			return err
+		}
+		files[filename(c)] = buf.Bytes()
+		return nil
+	}
+	if err := genFiles(cmd); err != nil {
+		return nil, err
+	}
+	return DiffDocFiles(dir, files)
+}
+
+// DiffDocFiles compares files, the generated content by file name, with the
+// files of dir. Only the files of dir with the extension of a generated file
+// are compared, so that other files may be kept next to the documentation.
+// The dates the documentation was generated on, in the "Auto generated by
+// spf13/cobra" tags and the headers of man pages, are ignored.
+func DiffDocFiles(dir string, files map[string][]byte) (*DocDrift, error) {
+	drift := &DocDrift{Diffs: make(map[string]string)}
+
+	extensions := make(map[string]bool)
+	for name := range files {
+		extensions[filepath.Ext(name)] = true
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	existing := make(map[string]bool)
+	for _, info := range infos {
+		if info.IsDir() || !extensions[filepath.Ext(info.Name())] {
+			continue
+		}
+		existing[info.Name()] = true
+		if _, ok := files[info.Name()]; !ok {
+			drift.Removed = append(drift.Removed, info.Name())
+		}
+	}
+
+	for name, generated := range files {
+		if !existing[name] {
+			drift.Added = append(drift.Added, name)
+			continue
+		}
+		current, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		from, to := driftLines(current), driftLines(generated)
+		if equalLines(from, to) {
+			continue
+		}
+		drift.Changed = append(drift.Changed, name)
+		drift.Diffs[name] = unifiedDiff("a/"+name, "b/"+name, from, to)
+	}
+
+	sort.Strings(drift.Added)
+	sort.Strings(drift.Removed)
+	sort.Strings(drift.Changed)
+	return drift, nil
+}
+
+// driftLines returns the lines of content, with the date the documentation
+// was generated replaced by driftDate.
+func driftLines(content []byte) []string {
+	var lines []string
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, ".TH ") || strings.HasPrefix(line, ":revdate:"):
+			line = headerDate.ReplaceAllLiteralString(line, driftDate)
+		case strings.HasPrefix(line, ".Dd "):
+			line = mdocDate.ReplaceAllLiteralString(line, driftDate)
+		case strings.Contains(line, "Auto generated by spf13/cobra"):
+			line = tagDate.ReplaceAllLiteralString(line, driftDate)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffOp is a line of a diff: ' ' for a line of both versions, '-' for a
+// removed line and '+' for an added line.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns the shortest edit script from a to b, computed from
+// their longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff returns the unified diff from a to b, with the file names
+// from and to.
+func unifiedDiff(from, to string, a, b []string) string {
+	ops := diffLines(a, b)
+	buf := new(bytes.Buffer)
+	buf.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", from, to))
+
+	for start := 0; start < len(ops); {
+		// Find the next change, and the end of its hunk, where more than
+		// twice the context of unchanged lines separate it from the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end, unchanged := start, 0
+		for i := start; i < len(ops) && unchanged <= 2*diffContext; i++ {
+			if ops[i].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+				end = i + 1
+			}
+		}
+
+		first := start - diffContext
+		if first < 0 {
+			first = 0
+		}
+		last := end + diffContext
+		if last > len(ops) {
+			last = len(ops)
+		}
+
+		// Line numbers of the hunk in both versions, starting at 1
+		aLine, bLine := 1, 1
+		for _, op := range ops[:first] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		hunk := new(bytes.Buffer)
+		for _, op := range ops[first:last] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+			line := op.line
+			if !strings.HasSuffix(line, "\n") {
+				line += "\n\\ No newline at end of file\n"
+			}
+			hunk.WriteString(string(op.kind) + line)
+		}
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+		buf.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount))
+		hunk.WriteTo(buf)
+		start = last
+	}
+	return buf.String()
+}
@@ -0,0 +1,170 @@
+package doc
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func markdownFilename(cmd *cobra.Command) string {
+	return strings.Replace(cmd.CommandPath(), " ", "_", -1) + ".md"
+}
+
+func TestDiffDocs(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-diff-docs")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenMarkdownTree(rootCmd, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	drift, err := DiffDocs(rootCmd, tmpdir, GenMarkdown, markdownFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift.HasDrift() {
+		t.Fatalf("Expected no drift, got:\n%s", drift)
+	}
+
+	// The date of the auto generated tag is ignored
+	page := filepath.Join(tmpdir, "root_echo.md")
+	content, err := ioutil.ReadFile(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = []byte(strings.Replace(string(content), time.Now().Format("2-Jan-2006"), "1-Jan-2000", 1))
+	if err := ioutil.WriteFile(page, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := DiffDocs(rootCmd, tmpdir, GenMarkdown, markdownFilename); err != nil || drift.HasDrift() {
+		t.Fatalf("Expected the date to be ignored, got %v:\n%s", err, drift)
+	}
+
+	for _, change := range []struct{ name, content string }{
+		{"root_echo.md", strings.Replace(string(content), echoCmd.Long, "an outdated description", 1)},
+		{"root_old.md", "## root old\n"},
+		{"README.txt", "not documentation\n"},
+	} {
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, change.name), []byte(change.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(tmpdir, "root_echo_times.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	drift, err = DiffDocs(rootCmd, tmpdir, GenMarkdown, markdownFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `added: root_echo_times.md
+removed: root_old.md
+changed: root_echo.md
+--- a/root_echo.md
++++ b/root_echo.md
+@@ -4,7 +4,7 @@
+ 
+ ### Synopsis
+ 
+-an outdated description
++an utterly useless command for testing
+ 
+ ### Examples
+ 
+`
+	if drift.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, drift)
+	}
+}
+
+func TestDiffDocsManHeader(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-diff-docs-man")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	header := &GenManHeader{Title: "ROOT", Section: "1"}
+	if err := GenManTree(rootCmd, header, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	gen := func(cmd *cobra.Command, w io.Writer) error {
+		return GenMan(cmd, header, w)
+	}
+	filename := func(cmd *cobra.Command) string {
+		return strings.Replace(cmd.CommandPath(), " ", "-", -1) + ".1"
+	}
+	page := filepath.Join(tmpdir, "root-echo.1")
+	content, err := ioutil.ReadFile(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thDate := time.Now().Format("Jan 2006")
+	if !strings.Contains(string(content), ".TH ROOT(1)"+thDate+"\n") {
+		t.Fatalf("Unexpected header:\n%s", content)
+	}
+
+	// The date of the header is ignored
+	outdated := strings.Replace(string(content), ".TH ROOT(1)"+thDate, ".TH ROOT(1)Jan 2000", 1)
+	if err := ioutil.WriteFile(page, []byte(outdated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := DiffDocs(rootCmd, tmpdir, gen, filename); err != nil || drift.HasDrift() {
+		t.Fatalf("Expected the date to be ignored, got %v:\n%s", err, drift)
+	}
+
+	// but not the rest of the header, such as the section
+	outdated = strings.Replace(string(content), ".TH ROOT(1)", ".TH ROOT(8)", 1)
+	if err := ioutil.WriteFile(page, []byte(outdated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	drift, err := DiffDocs(rootCmd, tmpdir, gen, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"root-echo.1"}; strings.Join(drift.Changed, " ") != strings.Join(expected, " ") {
+		t.Fatalf("Expected %v to change, got:\n%s", expected, drift)
+	}
+	checkStringContains(t, drift.Diffs["root-echo.1"], "-.TH ROOT(8)<date>\n+.TH ROOT(1)<date>\n")
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(s string) []string { return driftLines([]byte(s)) }
+	a := lines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20")
+	b := lines("1\n2\n3\n4\ntwo\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n16\n17\n18\n19\n20\n")
+
+	expected := `--- a
++++ b
+@@ -2,6 +2,7 @@
+ 2
+ 3
+ 4
++two
+ 5
+ 6
+ 7
+@@ -12,9 +13,8 @@
+ 12
+ 13
+ 14
+-15
+ 16
+ 17
+ 18
+ 19
+-20
+\ No newline at end of file
++20
+`
+	if got := unifiedDiff("a", "b", a, b); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
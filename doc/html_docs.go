@@ -0,0 +1,364 @@
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// HTMLSearchIndexFile is the name of the JSON search index GenHTMLTree writes
+// next to the pages. The same index is written as a script named
+// HTMLSearchIndexFile + ".js", which the pages load so that searching works
+// when they are opened from the filesystem.
+const HTMLSearchIndexFile = "search-index.json"
+
+// HTMLPage is the data the template of GenHTMLTreeCustom is executed with,
+// once for each command.
+type HTMLPage struct {
+	// Name is the path of the command (e.g., "root sub").
+	Name string
+	// File is the name of the file of the page (e.g., "root_sub.html").
+	File    string
+	Short   string
+	Long    string
+	UseLine string
+	Example string
+	// Runnable reports whether the command can be run, and has a UseLine.
+	Runnable bool
+	// Options are the flags of the command, InheritedOptions the flags
+	// inherited from parent commands.
+	Options          []HTMLFlag
+	InheritedOptions []HTMLFlag
+	// Parent is the page of the parent command, if any.
+	Parent      *HTMLLink
+	Subcommands []HTMLLink
+	// Nav is the command tree, with the root command as single item.
+	Nav []*HTMLNavItem
+	// SearchIndexScript is the name of the script defining the search index
+	// as the cobraSearchIndex variable.
+	SearchIndexScript string
+	// AutoGenTag is empty if DisableAutoGenTag is set.
+	AutoGenTag string
+	// Command is the command the page documents.
+	Command *cobra.Command
+}
+
+// HTMLFlag is a flag documented on an HTMLPage.
+type HTMLFlag struct {
+	Name      string
+	Shorthand string
+	// ValueName is the name of the value of the flag, empty for booleans.
+	ValueName string
+	Default   string
+	Usage     string
+	// Anchor is the id of the flag in the page (e.g., "flag-verbose").
+	Anchor string
+}
+
+// HTMLLink links to the page of a command.
+type HTMLLink struct {
+	Name  string
+	Short string
+	URL   string
+}
+
+// HTMLNavItem is a command in the navigation sidebar.
+type HTMLNavItem struct {
+	Name string
+	URL  string
+	// Current reports whether the item is the command of the page.
+	Current  bool
+	Children []*HTMLNavItem
+}
+
+// htmlSearchEntry is an entry of the search index.
+type htmlSearchEntry struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Summary string `json:"summary"`
+	URL     string `json:"url"`
+}
+
+// DefaultHTMLTemplate is the template GenHTMLTree executes for each page.
+// It is self-contained: the stylesheet and the search script are inlined.
+const DefaultHTMLTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<style>
+body { margin: 0; display: flex; font-family: sans-serif; line-height: 1.5; color: #222; }
+nav { width: 18em; min-height: 100vh; padding: 1em; background: #f5f5f5; border-right: 1px solid #ddd; box-sizing: border-box; }
+nav ul { list-style: none; margin: 0; padding-left: 1em; }
+nav > ul { padding-left: 0; }
+nav a.current { font-weight: bold; }
+nav input { width: 100%; box-sizing: border-box; }
+main { flex: 1; max-width: 60em; padding: 1em 2em; }
+a { color: #0b5394; text-decoration: none; }
+a:hover { text-decoration: underline; }
+pre { background: #f5f5f5; padding: 0.5em 1em; overflow-x: auto; }
+.long { white-space: pre-wrap; }
+dt code { font-weight: bold; }
+dt a.anchor { visibility: hidden; margin-left: 0.5em; }
+dt:hover a.anchor { visibility: visible; }
+dd { margin-bottom: 0.5em; }
+#search-results { padding-left: 0; }
+</style>
+</head>
+<body>
+<nav>
+<input id="search" type="search" placeholder="Search" aria-label="Search">
+<ul id="search-results"></ul>
+{{template "nav" .Nav}}
+</nav>
+<main>
+<h1>{{.Name}}</h1>
+<p>{{.Short}}</p>
+<h2 id="synopsis">Synopsis</h2>
+<p class="long">{{.Long}}</p>
+{{if .Runnable}}<pre><code>{{.UseLine}}</code></pre>
+{{end}}
+{{- if .Example}}<h2 id="examples">Examples</h2>
+<pre><code>{{.Example}}</code></pre>
+{{end}}
+{{- if .Options}}<h2 id="options">Options</h2>
+{{template "flags" .Options}}
+{{end}}
+{{- if .InheritedOptions}}<h2 id="inherited-options">Options inherited from parent commands</h2>
+{{template "flags" .InheritedOptions}}
+{{end}}
+{{- if or .Parent .Subcommands}}<h2 id="see-also">See also</h2>
+<ul>
+{{- with .Parent}}
+<li><a href="{{.URL}}">{{.Name}}</a> - {{.Short}}</li>
+{{- end}}
+{{- range .Subcommands}}
+<li><a href="{{.URL}}">{{.Name}}</a> - {{.Short}}</li>
+{{- end}}
+</ul>
+{{end}}
+{{- with .AutoGenTag}}<footer><p><em>{{.}}</em></p></footer>
+{{end -}}
+</main>
+<script src="{{.SearchIndexScript}}"></script>
+<script>
+(function() {
+  var input = document.getElementById("search");
+  var results = document.getElementById("search-results");
+  input.addEventListener("input", function() {
+    var query = input.value.toLowerCase().trim();
+    results.innerHTML = "";
+    if (!query || typeof cobraSearchIndex === "undefined") {
+      return;
+    }
+    cobraSearchIndex.filter(function(entry) {
+      return entry.name.toLowerCase().indexOf(query) !== -1 ||
+        entry.summary.toLowerCase().indexOf(query) !== -1;
+    }).slice(0, 20).forEach(function(entry) {
+      var item = document.createElement("li");
+      var link = document.createElement("a");
+      link.href = entry.url;
+      link.textContent = entry.name;
+      item.appendChild(link);
+      results.appendChild(item);
+    });
+  });
+})();
+</script>
+</body>
+</html>
+{{define "nav"}}<ul>
+{{- range .}}
+<li><a href="{{.URL}}"{{if .Current}} class="current"{{end}}>{{.Name}}</a>
+{{- if .Children}}{{template "nav" .Children}}{{end}}</li>
+{{- end}}
+</ul>{{end}}
+{{define "flags"}}<dl>
+{{- range .}}
+<dt id="{{.Anchor}}">{{if .Shorthand}}<code>-{{.Shorthand}}</code>, {{end}}<code>--{{.Name}}</code>{{with .ValueName}} <var>{{.}}</var>{{end}}<a class="anchor" href="#{{.Anchor}}">#</a></dt>
+<dd>{{.Usage}}{{with .Default}} (default <code>{{.}}</code>){{end}}</dd>
+{{- end}}
+</dl>{{end}}
+`
+
+// htmlPages maps the commands having a page to the name of their page.
+type htmlPages map[*cobra.Command]string
+
+func htmlFlags(flags *pflag.FlagSet) []HTMLFlag {
+	var result []HTMLFlag
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Deprecated) > 0 || flag.Hidden {
+			return
+		}
+		varname, usage := pflag.UnquoteUsage(helpFlag(flag))
+		f := HTMLFlag{
+			Name:      flag.Name,
+			ValueName: varname,
+			Usage:     usage,
+			Anchor:    "flag-" + flag.Name,
+		}
+		if len(flag.ShorthandDeprecated) == 0 {
+			f.Shorthand = flag.Shorthand
+		}
+		if flag.DefValue != "" && flag.DefValue != "[]" && !(varname == "" && flag.DefValue == "false") {
+			f.Default = flag.DefValue
+		}
+		result = append(result, f)
+	})
+	return result
+}
+
+// htmlNav returns the navigation item of cmd and its descendants having a
+// page, marking the item of current.
+func htmlNav(cmd, current *cobra.Command, pages htmlPages) *HTMLNavItem {
+	item := &HTMLNavItem{
+		Name:    cmd.Name(),
+		URL:     pages[cmd],
+		Current: cmd == current,
+	}
+	children := cmd.Commands()
+	sort.Sort(byName(children))
+	for _, c := range children {
+		if _, ok := pages[c]; ok {
+			item.Children = append(item.Children, htmlNav(c, current, pages))
+		}
+	}
+	return item
+}
+
+// htmlPage returns the page of cmd in the tree of root.
+func htmlPage(cmd, root *cobra.Command, pages htmlPages) *HTMLPage {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	page := &HTMLPage{
+		Name:              cmd.CommandPath(),
+		File:              pages[cmd],
+		Short:             cmd.Short,
+		Long:              cmd.Long,
+		UseLine:           cmd.UseLine(),
+		Example:           cmd.Example,
+		Runnable:          cmd.Runnable(),
+		Options:           htmlFlags(cmd.NonInheritedFlags()),
+		InheritedOptions:  htmlFlags(cmd.InheritedFlags()),
+		Nav:               []*HTMLNavItem{htmlNav(root, cmd, pages)},
+		SearchIndexScript: HTMLSearchIndexFile + ".js",
+		Command:           cmd,
+	}
+	if len(page.Long) == 0 {
+		page.Long = page.Short
+	}
+
+	disableAutoGenTag := cmd.DisableAutoGenTag
+	if cmd != root && cmd.HasParent() {
+		parent := cmd.Parent()
+		page.Parent = &HTMLLink{Name: parent.CommandPath(), Short: parent.Short, URL: pages[parent]}
+		cmd.VisitParents(func(c *cobra.Command) {
+			if c.DisableAutoGenTag {
+				disableAutoGenTag = true
+			}
+		})
+	}
+	children := cmd.Commands()
+	sort.Sort(byName(children))
+	for _, c := range children {
+		if file, ok := pages[c]; ok {
+			page.Subcommands = append(page.Subcommands, HTMLLink{Name: c.CommandPath(), Short: c.Short, URL: file})
+		}
+	}
+	if !disableAutoGenTag {
+		page.AutoGenTag = "Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006")
+	}
+	return page
+}
+
+// GenHTMLTree will generate a browsable HTML reference for this command and
+// all descendants in the directory given: a page for each command, named like
+// the pages of GenMarkdownTree with the ".html" extension, an "index.html"
+// page redirecting to the page of cmd, and a search index. The pages link to
+// each other with relative links and can be opened from the filesystem.
+func GenHTMLTree(cmd *cobra.Command, dir string) error {
+	return GenHTMLTreeCustom(cmd, dir, nil)
+}
+
+// GenHTMLTreeCustom is the same as GenHTMLTree, but executes tmpl, which
+// receives an *HTMLPage, for each page instead of DefaultHTMLTemplate. If tmpl
+// is nil, DefaultHTMLTemplate is used.
+func GenHTMLTreeCustom(cmd *cobra.Command, dir string, tmpl *template.Template) error {
+	return GenHTMLTreeFromOpts(cmd, TreeOptions{Dir: dir}, tmpl)
+}
+
+// GenHTMLTreeFromOpts is the same as GenHTMLTreeCustom, but with the commands,
+// the names of their pages and the output chosen by opts.  An error is returned,
+// before any file is written, if a page would replace index.html or the
+// search index.
+func GenHTMLTreeFromOpts(cmd *cobra.Command, opts TreeOptions, tmpl *template.Template) error {
+	if tmpl == nil {
+		var err error
+		if tmpl, err = template.New("page").Parse(DefaultHTMLTemplate); err != nil {
+			return err
+		}
+	}
+	opts = opts.withDefaults(defaultNaming, DefaultTreeFilter)
+	files, err := treeFiles(cmd, opts, ".html")
+	if err != nil {
+		return err
+	}
+	// The pages must not replace the other files of the tree
+	for _, file := range files {
+		for _, name := range []string{"index.html", HTMLSearchIndexFile, HTMLSearchIndexFile + ".js"} {
+			if file.name == name || (opts.CaseInsensitive && strings.EqualFold(file.name, name)) {
+				return fmt.Errorf("command %q has the file name %q of the generated %s", file.cmd.CommandPath(), file.name, name)
+			}
+		}
+	}
+	pages := make(htmlPages)
+	for _, file := range files {
+		pages[file.cmd] = file.name
+	}
+
+	var index []htmlSearchEntry
+	for _, file := range files {
+		page := htmlPage(file.cmd, cmd, pages)
+		err := cobra.WriteFile(opts.FS, file.name, func(w io.Writer) error {
+			return tmpl.Execute(w, page)
+		})
+		if err != nil {
+			return err
+		}
+
+		index = append(index, htmlSearchEntry{Name: page.Name, Kind: "command", Summary: page.Short, URL: page.File})
+		for _, f := range page.Options {
+			index = append(index, htmlSearchEntry{
+				Name:    page.Name + " --" + f.Name,
+				Kind:    "flag",
+				Summary: f.Usage,
+				URL:     page.File + "#" + f.Anchor,
+			})
+		}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTreeContent(opts.FS, HTMLSearchIndexFile, append(data, '\n')); err != nil {
+		return err
+	}
+	script := fmt.Sprintf("var cobraSearchIndex = %s;\n", data)
+	if err := writeTreeContent(opts.FS, HTMLSearchIndexFile+".js", []byte(script)); err != nil {
+		return err
+	}
+
+	redirect := fmt.Sprintf("<!DOCTYPE html>\n<meta charset=\"utf-8\">\n<meta http-equiv=\"refresh\" content=\"0; url=%[1]s\">\n<a href=\"%[1]s\">%[2]s</a>\n",
+		template.HTMLEscapeString(pages[cmd]), template.HTMLEscapeString(cmd.CommandPath()))
+	return writeTreeContent(opts.FS, "index.html", []byte(redirect))
+}
@@ -0,0 +1,108 @@
+package doc
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenHTMLTree(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-gen-html-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenHTMLTree(rootCmd, tmpdir); err != nil {
+		t.Fatalf("GenHTMLTree failed: %v", err)
+	}
+
+	for _, name := range []string{"root.html", "root_echo.html", "root_echo_echosub.html", "index.html", "search-index.json", "search-index.json.js"} {
+		if _, err := os.Stat(filepath.Join(tmpdir, name)); err != nil {
+			t.Fatalf("Expected file '%s' to exist", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpdir, "root_deprecated.html")); err == nil {
+		t.Fatalf("Expected no page for deprecated command")
+	}
+
+	page, err := ioutil.ReadFile(filepath.Join(tmpdir, "root_echo.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := string(page)
+	checkStringContains(t, output, echoCmd.Long)
+	checkStringContains(t, output, `<dt id="flag-boolone">`)
+	checkStringContains(t, output, `<a class="anchor" href="#flag-rootflag">`)
+	checkStringContains(t, output, `<a href="root_echo.html" class="current">echo</a>`)
+	checkStringContains(t, output, `<a href="root_echo_echosub.html">root echo echosub</a>`)
+	checkStringContains(t, output, `<script src="search-index.json.js">`)
+	checkStringOmits(t, output, deprecatedCmd.Short)
+
+	data, err := ioutil.ReadFile(filepath.Join(tmpdir, HTMLSearchIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index []htmlSearchEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("Invalid search index: %v", err)
+	}
+	expected := htmlSearchEntry{Name: "root echo --boolone", Kind: "flag", Summary: "help message for flag boolone", URL: "root_echo.html#flag-boolone"}
+	found := false
+	for _, entry := range index {
+		if entry == expected {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected %v in the search index, got %v", expected, index)
+	}
+}
+
+func TestGenHTMLTreeCustom(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-gen-html-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	tmpl := template.Must(template.New("page").Parse(`<h1>{{.Name}}</h1>{{range .Options}}<a href="#{{.Anchor}}">{{.Name}}</a>{{end}}`))
+	if err := GenHTMLTreeCustom(echoCmd, tmpdir, tmpl); err != nil {
+		t.Fatalf("GenHTMLTreeCustom failed: %v", err)
+	}
+
+	page, err := ioutil.ReadFile(filepath.Join(tmpdir, "root_echo_times.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, string(page), `<h1>root echo times</h1>`)
+	if _, err := os.Stat(filepath.Join(tmpdir, "root.html")); err == nil {
+		t.Fatalf("Expected no page for the parent of the command")
+	}
+}
+
+func TestGenHTMLTreeFileNameCollision(t *testing.T) {
+	root := &cobra.Command{Use: "index", Run: emptyRun}
+	files := cobra.NewMemFS()
+	err := GenHTMLTreeFromOpts(root, TreeOptions{FS: files}, nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), `command "index" has the file name "index.html"`)
+	if got := fileNames(files); got != "" {
+		t.Errorf("Expected no file to be written, got %q", got)
+	}
+
+	root = &cobra.Command{Use: "Index", Run: emptyRun}
+	if err := GenHTMLTreeFromOpts(root, TreeOptions{FS: cobra.NewMemFS()}, nil); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := GenHTMLTreeFromOpts(root, TreeOptions{FS: cobra.NewMemFS(), CaseInsensitive: true}, nil); err == nil {
+		t.Error("Expected an error for names differing in case")
+	}
+}
@@ -0,0 +1,378 @@
+package doc
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// JSONDocVersion is the version of the document model GenJSON writes. It is
+// incremented whenever a change of the model may break its consumers.
+const JSONDocVersion = 1
+
+// JSONDoc is the document GenJSON writes, validated by JSONDocSchema.
+type JSONDoc struct {
+	Version int          `json:"version"`
+	Command *JSONCommand `json:"command"`
+}
+
+// JSONCommand documents a command.
+type JSONCommand struct {
+	Name             string            `json:"name"`
+	Path             string            `json:"path"`
+	Usage            string            `json:"usage,omitempty"`
+	Aliases          []string          `json:"aliases,omitempty"`
+	Short            string            `json:"short,omitempty"`
+	Long             string            `json:"long,omitempty"`
+	Example          string            `json:"example,omitempty"`
+	Runnable         bool              `json:"runnable,omitempty"`
+	Hidden           bool              `json:"hidden,omitempty"`
+	Deprecated       string            `json:"deprecated,omitempty"`
+	HelpTopic        bool              `json:"help_topic,omitempty"`
+	Annotations      map[string]string `json:"annotations,omitempty"`
+	Args             *JSONArgs         `json:"args,omitempty"`
+	Options          []JSONFlag        `json:"options,omitempty"`
+	InheritedOptions []JSONFlag        `json:"inherited_options,omitempty"`
+	Subcommands      []*JSONCommand    `json:"subcommands,omitempty"`
+}
+
+// JSONArgs documents the positional arguments a command accepts.
+type JSONArgs struct {
+	// Min is the minimum number of arguments.
+	Min int `json:"min"`
+	// Max is the maximum number of arguments, nil if unbounded.
+	Max *int `json:"max,omitempty"`
+	// ValidArgs are the values of cobra.Command.ValidArgs.
+	ValidArgs []JSONValue `json:"valid_args,omitempty"`
+	// OnlyValidArgs reports whether arguments other than ValidArgs are rejected.
+	OnlyValidArgs bool     `json:"only_valid_args,omitempty"`
+	ArgAliases    []string `json:"arg_aliases,omitempty"`
+	// Unknown reports whether the arguments are validated by a function other
+	// than the validators of cobra, in which case Min, Max and OnlyValidArgs
+	// are not known.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// JSONFlag documents a flag.
+type JSONFlag struct {
+	Name                string      `json:"name"`
+	Shorthand           string      `json:"shorthand,omitempty"`
+	Type                string      `json:"type"`
+	DefaultValue        string      `json:"default_value,omitempty"`
+	NoOptDefaultValue   string      `json:"no_opt_default_value,omitempty"`
+	Usage               string      `json:"usage,omitempty"`
+	Required            bool        `json:"required,omitempty"`
+	Hidden              bool        `json:"hidden,omitempty"`
+	Deprecated          string      `json:"deprecated,omitempty"`
+	ShorthandDeprecated string      `json:"shorthand_deprecated,omitempty"`
+	AllowedValues       []JSONValue `json:"allowed_values,omitempty"`
+}
+
+// JSONValue is a value accepted by an argument or a flag.
+type JSONValue struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// JSONDocSchema is the JSON Schema of the documents GenJSON and GenJSONTree
+// write. It is also available as the json_docs.schema.json file.
+const JSONDocSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "cobra command documentation",
+  "description": "The documentation of a cobra command, as written by doc.GenJSON and doc.GenJSONTree.",
+  "type": "object",
+  "required": ["version", "command"],
+  "properties": {
+    "version": {
+      "description": "The version of the document model.",
+      "const": 1
+    },
+    "command": {
+      "$ref": "#/definitions/command"
+    }
+  },
+  "additionalProperties": false,
+  "definitions": {
+    "command": {
+      "type": "object",
+      "required": ["name", "path"],
+      "properties": {
+        "name": {
+          "description": "The name of the command.",
+          "type": "string"
+        },
+        "path": {
+          "description": "The full path of the command, starting with the root command.",
+          "type": "string"
+        },
+        "usage": {
+          "description": "The usage line of the command.",
+          "type": "string"
+        },
+        "aliases": {
+          "type": "array",
+          "items": {"type": "string"}
+        },
+        "short": {"type": "string"},
+        "long": {"type": "string"},
+        "example": {"type": "string"},
+        "runnable": {
+          "description": "Whether the command can be run, rather than only grouping subcommands.",
+          "type": "boolean"
+        },
+        "hidden": {"type": "boolean"},
+        "deprecated": {
+          "description": "The deprecation message of the command.",
+          "type": "string"
+        },
+        "help_topic": {
+          "description": "Whether the command is an additional help topic.",
+          "type": "boolean"
+        },
+        "annotations": {
+          "type": "object",
+          "additionalProperties": {"type": "string"}
+        },
+        "args": {
+          "$ref": "#/definitions/args"
+        },
+        "options": {
+          "description": "The flags of the command.",
+          "type": "array",
+          "items": {"$ref": "#/definitions/flag"}
+        },
+        "inherited_options": {
+          "description": "The flags inherited from parent commands.",
+          "type": "array",
+          "items": {"$ref": "#/definitions/flag"}
+        },
+        "subcommands": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/command"}
+        }
+      },
+      "additionalProperties": false
+    },
+    "args": {
+      "description": "The positional arguments the command accepts.",
+      "type": "object",
+      "required": ["min"],
+      "properties": {
+        "min": {
+          "type": "integer",
+          "minimum": 0
+        },
+        "max": {
+          "description": "The maximum number of arguments, absent if unbounded.",
+          "type": "integer",
+          "minimum": 0
+        },
+        "valid_args": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/value"}
+        },
+        "only_valid_args": {
+          "description": "Whether arguments other than valid_args are rejected.",
+          "type": "boolean"
+        },
+        "arg_aliases": {
+          "type": "array",
+          "items": {"type": "string"}
+        },
+        "unknown": {
+          "description": "Whether the arguments are validated by a function other than the validators of cobra, in which case min, max and only_valid_args are not known.",
+          "type": "boolean"
+        }
+      },
+      "additionalProperties": false
+    },
+    "flag": {
+      "type": "object",
+      "required": ["name", "type"],
+      "properties": {
+        "name": {"type": "string"},
+        "shorthand": {"type": "string"},
+        "type": {
+          "description": "The type of the value of the flag, such as \"string\", \"bool\" or \"stringSlice\".",
+          "type": "string"
+        },
+        "default_value": {"type": "string"},
+        "no_opt_default_value": {
+          "description": "The value of the flag when it is given without a value.",
+          "type": "string"
+        },
+        "usage": {"type": "string"},
+        "required": {"type": "boolean"},
+        "hidden": {"type": "boolean"},
+        "deprecated": {
+          "description": "The deprecation message of the flag.",
+          "type": "string"
+        },
+        "shorthand_deprecated": {
+          "description": "The deprecation message of the shorthand of the flag.",
+          "type": "string"
+        },
+        "allowed_values": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/value"}
+        }
+      },
+      "additionalProperties": false
+    },
+    "value": {
+      "type": "object",
+      "required": ["value"],
+      "properties": {
+        "value": {"type": "string"},
+        "description": {"type": "string"}
+      },
+      "additionalProperties": false
+    }
+  }
+}
+`
+
+// GenJSON creates a JSON document of this command and all its descendants.
+func GenJSON(cmd *cobra.Command, w io.Writer) error {
+	return writeJSONDoc(w, genJSONCommand(cmd, true))
+}
+
+// GenJSONTree creates a JSON document for this command and all descendants
+// in the directory given, named like the files of GenYamlTree with the
+// ".json" extension. In each document, the subcommands only hold their name,
+// path, short description and hidden and deprecated state: they are
+// documented in their own file.
+func GenJSONTree(cmd *cobra.Command, dir string) error {
+	return GenJSONTreeFromOpts(cmd, TreeOptions{Dir: dir})
+}
+
+// GenJSONTreeFromOpts is the same as GenJSONTree, but
+// with the commands, the names of their files and the output chosen by opts.
+func GenJSONTreeFromOpts(cmd *cobra.Command, opts TreeOptions) error {
+	return genTree(cmd, opts, defaultNaming, DefaultTreeFilter, ".json", func(c *cobra.Command, filename string, w io.Writer) error {
+		return writeJSONDoc(w, genJSONCommand(c, false))
+	})
+}
+
+func writeJSONDoc(w io.Writer, command *JSONCommand) error {
+	final, err := json.MarshalIndent(&JSONDoc{Version: JSONDocVersion, Command: command}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(final, '\n'))
+	return err
+}
+
+// genJSONCommand documents cmd. Its subcommands are fully documented if
+// recursive is set, and summarized otherwise.
+func genJSONCommand(cmd *cobra.Command, recursive bool) *JSONCommand {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	jsonCmd := &JSONCommand{
+		Name:             cmd.Name(),
+		Path:             cmd.CommandPath(),
+		Usage:            cmd.UseLine(),
+		Aliases:          cmd.Aliases,
+		Short:            cmd.Short,
+		Long:             cmd.Long,
+		Example:          cmd.Example,
+		Runnable:         cmd.Runnable(),
+		Hidden:           cmd.Hidden,
+		Deprecated:       cmd.Deprecated,
+		HelpTopic:        cmd.IsAdditionalHelpTopicCommand(),
+		Annotations:      cmd.Annotations,
+		Args:             genJSONArgs(cmd),
+		Options:          genJSONFlags(cmd.NonInheritedFlags()),
+		InheritedOptions: genJSONFlags(cmd.InheritedFlags()),
+	}
+
+	children := cmd.Commands()
+	sort.Sort(byName(children))
+	for _, c := range children {
+		if recursive {
+			jsonCmd.Subcommands = append(jsonCmd.Subcommands, genJSONCommand(c, true))
+			continue
+		}
+		jsonCmd.Subcommands = append(jsonCmd.Subcommands, &JSONCommand{
+			Name:       c.Name(),
+			Path:       c.CommandPath(),
+			Short:      c.Short,
+			Hidden:     c.Hidden,
+			Deprecated: c.Deprecated,
+			HelpTopic:  c.IsAdditionalHelpTopicCommand(),
+		})
+	}
+	return jsonCmd
+}
+
+// genJSONArgs documents the arguments of cmd.  The number of arguments
+// accepted is only known for the validators of cobra, see cobra.ArityOf;
+// other functions are never called.
+func genJSONArgs(cmd *cobra.Command) *JSONArgs {
+	if cmd.Args == nil && len(cmd.ValidArgs) == 0 && len(cmd.ArgAliases) == 0 {
+		return nil
+	}
+
+	args := &JSONArgs{ArgAliases: cmd.ArgAliases}
+	for _, v := range cmd.ValidArgs {
+		args.ValidArgs = append(args.ValidArgs, genJSONValue(v))
+	}
+	if cmd.Args == nil {
+		return args
+	}
+	arity, ok := cobra.ArityOf(cmd.Args)
+	if !ok {
+		args.Unknown = true
+		return args
+	}
+	args.Min = arity.Min
+	if arity.Max >= 0 {
+		max := arity.Max
+		args.Max = &max
+	}
+	args.OnlyValidArgs = arity.OnlyValidArgs && len(args.ValidArgs) > 0
+	return args
+}
+
+func genJSONFlags(flags *pflag.FlagSet) []JSONFlag {
+	var result []JSONFlag
+	flags.VisitAll(func(flag *pflag.Flag) {
+		jsonFlag := JSONFlag{
+			Name:                flag.Name,
+			Shorthand:           flag.Shorthand,
+			Type:                flag.Value.Type(),
+			DefaultValue:        flag.DefValue,
+			Usage:               flag.Usage,
+			Hidden:              flag.Hidden,
+			Deprecated:          flag.Deprecated,
+			ShorthandDeprecated: flag.ShorthandDeprecated,
+		}
+		if flag.NoOptDefVal != "" && flag.Value.Type() != "bool" {
+			jsonFlag.NoOptDefaultValue = flag.NoOptDefVal
+		}
+		if required := flag.Annotations[cobra.BashCompOneRequiredFlag]; len(required) > 0 && required[0] == "true" {
+			jsonFlag.Required = true
+		}
+		for _, v := range flag.Annotations[cobra.FlagEnumValues] {
+			jsonFlag.AllowedValues = append(jsonFlag.AllowedValues, genJSONValue(v))
+		}
+		result = append(result, jsonFlag)
+	})
+	return result
+}
+
+// genJSONValue documents a value of cobra.Command.ValidArgs or of
+// cobra.MarkFlagEnum, which may be followed by a tab and its description.
+func genJSONValue(v string) JSONValue {
+	value := strings.SplitN(v, "\t", 2)
+	jsonValue := JSONValue{Value: value[0]}
+	if len(value) == 2 {
+		jsonValue.Description = value[1]
+	}
+	return jsonValue
+}
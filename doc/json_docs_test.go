@@ -0,0 +1,247 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenJSONDoc(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenJSON(rootCmd, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc JSONDoc
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc.Version != JSONDocVersion {
+		t.Errorf("Expected version %d, got %d", JSONDocVersion, doc.Version)
+	}
+
+	echo := findJSONCommand(doc.Command, "root echo")
+	if echo == nil {
+		t.Fatal("Expected the echo command to be documented")
+	}
+	if len(echo.Aliases) != 1 || echo.Aliases[0] != "say" {
+		t.Errorf("Expected the aliases of echo, got %v", echo.Aliases)
+	}
+	if findJSONCommand(doc.Command, "root echo echosub") == nil {
+		t.Error("Expected the subcommands of echo to be documented")
+	}
+	if deprecated := findJSONCommand(doc.Command, "root echo deprecated"); deprecated == nil || deprecated.Deprecated != deprecatedCmd.Deprecated {
+		t.Errorf("Expected the deprecated command to be marked as deprecated, got %+v", deprecated)
+	}
+
+	var boolone *JSONFlag
+	for i := range echo.Options {
+		if echo.Options[i].Name == "boolone" {
+			boolone = &echo.Options[i]
+		}
+	}
+	if boolone == nil || boolone.Type != "bool" || boolone.Shorthand != "b" || boolone.DefaultValue != "true" {
+		t.Errorf("Expected the boolone flag to be documented, got %+v", boolone)
+	}
+}
+
+func TestGenJSONArgs(t *testing.T) {
+	c := &cobra.Command{Use: "do", Run: emptyRun}
+	testcases := []struct {
+		args     cobra.PositionalArgs
+		expected string
+	}{
+		{cobra.NoArgs, `{"min":0,"max":0,"valid_args":[{"value":"one","description":"the first"},{"value":"two"}]}`},
+		{cobra.RangeArgs(1, 2), `{"min":1,"max":2,"valid_args":[{"value":"one","description":"the first"},{"value":"two"}]}`},
+		{cobra.MinimumNArgs(1), `{"min":1,"valid_args":[{"value":"one","description":"the first"},{"value":"two"}]}`},
+		{cobra.ExactValidArgs(1), `{"min":1,"max":1,"valid_args":[{"value":"one","description":"the first"},{"value":"two"}],"only_valid_args":true}`},
+		{cobra.OnlyValidArgs, `{"min":0,"valid_args":[{"value":"one","description":"the first"},{"value":"two"}],"only_valid_args":true}`},
+		{cobra.MinimumNArgs(20), `{"min":20,"valid_args":[{"value":"one","description":"the first"},{"value":"two"}]}`},
+		{cobra.MaximumNArgs(20), `{"min":0,"max":20,"valid_args":[{"value":"one","description":"the first"},{"value":"two"}]}`},
+	}
+	for _, tc := range testcases {
+		c.Args = tc.args
+		c.ValidArgs = []string{"one\tthe first", "two"}
+		output, err := json.Marshal(genJSONArgs(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, output)
+		}
+	}
+}
+
+func TestGenJSONArgsUnknown(t *testing.T) {
+	called := false
+	c := &cobra.Command{
+		Use: "do",
+		Args: func(cmd *cobra.Command, args []string) error {
+			called = true
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		Run: emptyRun,
+	}
+	output, err := json.Marshal(genJSONArgs(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"min":0,"unknown":true}`; string(output) != expected {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+	if called {
+		t.Error("Expected Args not to be called")
+	}
+}
+
+func TestGenJSONRequiredAndEnumFlags(t *testing.T) {
+	c := &cobra.Command{Use: "do", Run: emptyRun}
+	c.Flags().String("output", "json", "output format")
+	if err := c.MarkFlagRequired("output"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.MarkFlagEnum("output", "json\tas JSON", "yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	var flag JSONFlag
+	for _, f := range genJSONCommand(c, false).Options {
+		if f.Name == "output" {
+			flag = f
+		}
+	}
+	if !flag.Required || flag.Type != "string" {
+		t.Errorf("Expected a required string flag, got %+v", flag)
+	}
+	expected := []JSONValue{{Value: "json", Description: "as JSON"}, {Value: "yaml"}}
+	if len(flag.AllowedValues) != 2 || flag.AllowedValues[0] != expected[0] || flag.AllowedValues[1] != expected[1] {
+		t.Errorf("Expected allowed values %v, got %v", expected, flag.AllowedValues)
+	}
+}
+
+func TestGenJSONTree(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-gen-json-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenJSONTree(rootCmd, tmpdir); err != nil {
+		t.Fatalf("GenJSONTree failed: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(tmpdir, "root_echo.json"))
+	if err != nil {
+		t.Fatalf("Expected file 'root_echo.json' to exist")
+	}
+	var doc JSONDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	for _, sub := range doc.Command.Subcommands {
+		if sub.Options != nil || sub.Subcommands != nil {
+			t.Errorf("Expected the subcommand %q to be summarized", sub.Path)
+		}
+	}
+}
+
+func TestJSONDocSchema(t *testing.T) {
+	schemaFile, err := ioutil.ReadFile("json_docs.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(schemaFile) != JSONDocSchema {
+		t.Fatal("json_docs.schema.json and JSONDocSchema differ")
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(schemaFile, &schema); err != nil {
+		t.Fatalf("Invalid schema: %v", err)
+	}
+
+	rootCmd.Annotations = map[string]string{"key": "value"}
+	defer func() { rootCmd.Annotations = nil }()
+	buf := new(bytes.Buffer)
+	if err := GenJSON(rootCmd, buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	checkJSONSchema(t, schema, schema, doc, "")
+}
+
+func findJSONCommand(cmd *JSONCommand, path string) *JSONCommand {
+	if cmd.Path == path {
+		return cmd
+	}
+	for _, sub := range cmd.Subcommands {
+		if found := findJSONCommand(sub, path); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// checkJSONSchema checks value against the subset of JSON Schema that
+// JSONDocSchema uses.
+func checkJSONSchema(t *testing.T, root, schema map[string]interface{}, value interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		def := strings.TrimPrefix(ref, "#/definitions/")
+		schema = root["definitions"].(map[string]interface{})[def].(map[string]interface{})
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if schema["type"] != "object" {
+			t.Errorf("%s: unexpected object", path)
+			return
+		}
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[name.(string)]; !ok {
+					t.Errorf("%s: missing %s", path, name)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, prop := range v {
+			propSchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				propSchema, ok = schema["additionalProperties"].(map[string]interface{})
+			}
+			if !ok {
+				t.Errorf("%s: unexpected property %s", path, name)
+				continue
+			}
+			checkJSONSchema(t, root, propSchema, prop, path+"/"+name)
+		}
+	case []interface{}:
+		if schema["type"] != "array" {
+			t.Errorf("%s: unexpected array", path)
+			return
+		}
+		for i, item := range v {
+			checkJSONSchema(t, root, schema["items"].(map[string]interface{}), item, path+"/"+strconv.Itoa(i))
+		}
+	case string:
+		if schema["type"] != "string" {
+			t.Errorf("%s: unexpected string", path)
+		}
+	case bool:
+		if schema["type"] != "boolean" {
+			t.Errorf("%s: unexpected boolean", path)
+		}
+	case float64:
+		if schema["type"] != "integer" && schema["const"] != v {
+			t.Errorf("%s: unexpected number", path)
+		}
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -30,10 +29,9 @@ import (
 )
 
 // GenManTree will generate a man page for this command and all descendants
-// in the directory given. The header may be nil. This function may not work
-// correctly if your command names have `-` in them. If you have `cmd` with two
-// subcmds, `sub` and `sub-third`, and `sub` has a subcommand called `third`
-// it is undefined which help output will be in the file `cmd-sub-third.1`.
+// in the directory given. The header may be nil. It returns an error if two
+// commands have the same file name, such as `cmd sub-third` and
+// `cmd sub third`, see GenManTreeFromOpts to name the files differently.
 func GenManTree(cmd *cobra.Command, header *GenManHeader, dir string) error {
 	return GenManTreeFromOpts(cmd, GenManTreeOptions{
 		Header:           header,
@@ -43,47 +41,52 @@ func GenManTree(cmd *cobra.Command, header *GenManHeader, dir string) error {
 }
 
 // GenManTreeFromOpts generates a man page for the command and all descendants.
-// The pages are written to the opts.Path directory.
+// The pages are written to the opts.Path directory, unless opts.FS is set.
 func GenManTreeFromOpts(cmd *cobra.Command, opts GenManTreeOptions) error {
+	return genManTree(cmd, opts, "", GenMan)
+}
+
+// genManTree writes the pages of cmd and its descendants with gen, named
+// after the command and their section, followed by ext.
+func genManTree(cmd *cobra.Command, opts GenManTreeOptions, ext string, gen func(*cobra.Command, *GenManHeader, io.Writer) error) error {
 	header := opts.Header
 	if header == nil {
 		header = &GenManHeader{}
 	}
-	for _, c := range cmd.Commands() {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-			continue
-		}
-		if err := GenManTreeFromOpts(c, opts); err != nil {
-			return err
-		}
-	}
-	section := "1"
-	if header.Section != "" {
-		section = header.Section
-	}
-
 	separator := "_"
 	if opts.CommandSeparator != "" {
 		separator = opts.CommandSeparator
 	}
-	basename := strings.Replace(cmd.CommandPath(), " ", separator, -1)
-	filename := filepath.Join(opts.Path, basename+"."+section)
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
+	naming := namingOr(opts.Naming, JoinNaming(separator))
+	treeOpts := TreeOptions{
+		// The section of a page is part of its name
+		Naming:          func(c *cobra.Command) string { return naming(c) + "." + manPageSection(header, c) },
+		Filter:          opts.Filter,
+		FS:              opts.FS,
+		Dir:             opts.Path,
+		CaseInsensitive: opts.CaseInsensitive,
 	}
-	defer f.Close()
-
-	headerCopy := *header
-	return GenMan(cmd, &headerCopy, f)
+	manFilter := func(c *cobra.Command) bool { return DefaultTreeFilter(c) || isManHelpTopic(c) }
+	return genTree(cmd, treeOpts, naming, manFilter, ext, func(c *cobra.Command, filename string, w io.Writer) error {
+		headerCopy := *header
+		return gen(c, &headerCopy, w)
+	})
 }
 
 // GenManTreeOptions is the options for generating the man pages.
 // Used only in GenManTreeFromOpts.
 type GenManTreeOptions struct {
-	Header           *GenManHeader
-	Path             string
+	Header *GenManHeader
+	Path   string
+	// CommandSeparator joins the names of the commands in the names of the
+	// pages, if Naming is nil. Defaults to "_".
 	CommandSeparator string
+	// Naming, Filter, FS and CaseInsensitive are like the fields of
+	// TreeOptions. The section of a page is appended to the name Naming returns.
+	Naming          NamingStrategy
+	Filter          func(*cobra.Command) bool
+	FS              cobra.WriteFS
+	CaseInsensitive bool
 }
 
 // GenManHeader is a lot like the .TH header at the start of man pages. These
@@ -97,6 +100,158 @@ type GenManHeader struct {
 	date    string
 	Source  string
 	Manual  string
+
+	// ExitStatus, Environment and Files are listed in the EXIT STATUS,
+	// ENVIRONMENT and FILES sections of every page, followed by the entries
+	// of the ManExitStatusAnnotation, ManEnvironmentAnnotation and
+	// ManFilesAnnotation annotations of the command.
+	ExitStatus  []ManEntry
+	Environment []ManEntry
+	Files       []ManEntry
+	// Bugs is the content of the BUGS section of every page, unless the
+	// command has a ManBugsAnnotation annotation.
+	Bugs string
+	// Sections are additional sections of every page, written after BUGS,
+	// followed by the sections of the ManSectionAnnotationPrefix annotations
+	// of the command.
+	Sections []ManSection
+}
+
+// Annotations of a command adding to the sections of its man page. The
+// entries of ManExitStatusAnnotation, ManEnvironmentAnnotation and
+// ManFilesAnnotation are each on their own line, their name followed by a
+// tab character and their description, e.g.:
+//
+//	cmd.Annotations[doc.ManEnvironmentAnnotation] = "EDITOR\tthe editor to use\nPAGER\tthe pager to use"
+//
+// A ManSectionAnnotationPrefix annotation adds the section named after the
+// prefix, e.g. "cobra_annotation_man_section_NOTES" adds a NOTES section.
+const (
+	ManExitStatusAnnotation    = "cobra_annotation_man_exit_status"
+	ManEnvironmentAnnotation   = "cobra_annotation_man_environment"
+	ManFilesAnnotation         = "cobra_annotation_man_files"
+	ManBugsAnnotation          = "cobra_annotation_man_bugs"
+	ManSectionAnnotationPrefix = "cobra_annotation_man_section_"
+)
+
+// manHelpTopicSection is the section of the pages of additional help topic
+// commands, the section of miscellaneous pages.
+const manHelpTopicSection = "7"
+
+// ManEntry is an exit code, environment variable or file described in a
+// man page.
+type ManEntry struct {
+	Name        string
+	Description string
+}
+
+// ManSection is an additional section of a man page. Its content is
+// markdown, like the description of the command.
+type ManSection struct {
+	Name    string
+	Content string
+}
+
+// manSections holds the additional sections of the man page of a command.
+type manSections struct {
+	exitStatus  []ManEntry
+	environment []ManEntry
+	files       []ManEntry
+	bugs        string
+	custom      []ManSection
+}
+
+// collectManSections merges the additional sections of header and the
+// annotations of cmd. Entries and sections of cmd replace those of header
+// with the same name.
+func collectManSections(header *GenManHeader, cmd *cobra.Command) manSections {
+	sections := manSections{
+		exitStatus:  mergeManEntries(header.ExitStatus, cmd.Annotations[ManExitStatusAnnotation]),
+		environment: mergeManEntries(header.Environment, cmd.Annotations[ManEnvironmentAnnotation]),
+		files:       mergeManEntries(header.Files, cmd.Annotations[ManFilesAnnotation]),
+		bugs:        header.Bugs,
+		custom:      append([]ManSection(nil), header.Sections...),
+	}
+	if bugs, ok := cmd.Annotations[ManBugsAnnotation]; ok {
+		sections.bugs = bugs
+	}
+
+	var names []string
+	for key := range cmd.Annotations {
+		if strings.HasPrefix(key, ManSectionAnnotationPrefix) {
+			names = append(names, strings.TrimPrefix(key, ManSectionAnnotationPrefix))
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		section := ManSection{Name: name, Content: cmd.Annotations[ManSectionAnnotationPrefix+name]}
+		replaced := false
+		for i := range sections.custom {
+			if sections.custom[i].Name == name {
+				sections.custom[i] = section
+				replaced = true
+			}
+		}
+		if !replaced {
+			sections.custom = append(sections.custom, section)
+		}
+	}
+	return sections
+}
+
+// mergeManEntries returns entries followed by the entries of annotation,
+// which replace the entries with the same name.
+func mergeManEntries(entries []ManEntry, annotation string) []ManEntry {
+	result := append([]ManEntry(nil), entries...)
+	for _, line := range strings.Split(annotation, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 2)
+		entry := ManEntry{Name: fields[0]}
+		if len(fields) == 2 {
+			entry.Description = fields[1]
+		}
+		replaced := false
+		for i := range result {
+			if result[i].Name == entry.Name {
+				result[i] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// isManHelpTopic reports whether cmd is an additional help topic command,
+// which gets a page of its own in the manHelpTopicSection section.
+func isManHelpTopic(cmd *cobra.Command) bool {
+	return cmd.HasParent() && cmd.IsAdditionalHelpTopicCommand()
+}
+
+// hasManHelpTopics reports whether cmd has additional help topic commands,
+// which its man page refers to.
+func hasManHelpTopics(cmd *cobra.Command) bool {
+	for _, c := range cmd.Commands() {
+		if isManHelpTopic(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// manPageSection returns the section of the page of cmd.
+func manPageSection(header *GenManHeader, cmd *cobra.Command) string {
+	if isManHelpTopic(cmd) {
+		return manHelpTopicSection
+	}
+	if header.Section != "" {
+		return header.Section
+	}
+	return "1"
 }
 
 // GenMan will generate a man page for the given command and write it to
@@ -149,10 +304,12 @@ func manPreamble(buf *bytes.Buffer, header *GenManHeader, cmd *cobra.Command, da
 %% %s
 %% %s
 # NAME
-`, header.Title, header.Section, header.date, header.Source, header.Manual))
+`, header.Title, manPageSection(header, cmd), header.date, header.Source, header.Manual))
 	buf.WriteString(fmt.Sprintf("%s \\- %s\n\n", dashedName, cmd.Short))
-	buf.WriteString("# SYNOPSIS\n")
-	buf.WriteString(fmt.Sprintf("**%s**\n\n", cmd.UseLine()))
+	if !isManHelpTopic(cmd) {
+		buf.WriteString("# SYNOPSIS\n")
+		buf.WriteString(fmt.Sprintf("**%s**\n\n", cmd.UseLine()))
+	}
 	buf.WriteString("# DESCRIPTION\n")
 	buf.WriteString(description + "\n\n")
 }
@@ -181,7 +338,7 @@ func manPrintFlags(buf *bytes.Buffer, flags *pflag.FlagSet) {
 			format += "]"
 		}
 		format += "\n\t%s\n\n"
-		buf.WriteString(fmt.Sprintf(format, flag.DefValue, flag.Usage))
+		buf.WriteString(fmt.Sprintf(format, flag.DefValue, cobra.EnumFlagUsage(flag)))
 	})
 }
 
@@ -200,6 +357,19 @@ func manPrintOptions(buf *bytes.Buffer, command *cobra.Command) {
 	}
 }
 
+// manPrintEntries writes the section title listing entries, their names
+// formatted with nameFormat.
+func manPrintEntries(buf *bytes.Buffer, title string, entries []ManEntry, nameFormat string) {
+	if len(entries) == 0 {
+		return
+	}
+	buf.WriteString("# " + title + "\n")
+	for _, entry := range entries {
+		buf.WriteString(fmt.Sprintf(nameFormat+"\n\t%s\n\n", entry.Name, entry.Description))
+	}
+	buf.WriteString("\n")
+}
+
 func genMan(cmd *cobra.Command, header *GenManHeader) []byte {
 	cmd.InitDefaultHelpCmd()
 	cmd.InitDefaultHelpFlag()
@@ -210,12 +380,26 @@ func genMan(cmd *cobra.Command, header *GenManHeader) []byte {
 	buf := new(bytes.Buffer)
 
 	manPreamble(buf, header, cmd, dashCommandName)
-	manPrintOptions(buf, cmd)
+	if !isManHelpTopic(cmd) {
+		manPrintOptions(buf, cmd)
+	}
+	sections := collectManSections(header, cmd)
+	manPrintEntries(buf, "EXIT STATUS", sections.exitStatus, "**%s**")
+	manPrintEntries(buf, "ENVIRONMENT", sections.environment, "**%s**")
+	manPrintEntries(buf, "FILES", sections.files, "*%s*")
 	if len(cmd.Example) > 0 {
 		buf.WriteString("# EXAMPLE\n")
 		buf.WriteString(fmt.Sprintf("```\n%s\n```\n", cmd.Example))
 	}
-	if hasSeeAlso(cmd) {
+	if sections.bugs != "" {
+		buf.WriteString("# BUGS\n")
+		buf.WriteString(sections.bugs + "\n\n")
+	}
+	for _, section := range sections.custom {
+		buf.WriteString("# " + section.Name + "\n")
+		buf.WriteString(section.Content + "\n\n")
+	}
+	if hasSeeAlso(cmd, DefaultTreeFilter) || hasManHelpTopics(cmd) {
 		buf.WriteString("# SEE ALSO\n")
 		seealsos := make([]string, 0)
 		if cmd.HasParent() {
@@ -232,10 +416,10 @@ func genMan(cmd *cobra.Command, header *GenManHeader) []byte {
 		children := cmd.Commands()
 		sort.Sort(byName(children))
 		for _, c := range children {
-			if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			if (!c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand()) && !isManHelpTopic(c) {
 				continue
 			}
-			seealso := fmt.Sprintf("**%s-%s(%s)**", dashCommandName, c.Name(), header.Section)
+			seealso := fmt.Sprintf("**%s-%s(%s)**", dashCommandName, c.Name(), manPageSection(header, c))
 			seealsos = append(seealsos, seealso)
 		}
 		buf.WriteString(strings.Join(seealsos, ", ") + "\n")
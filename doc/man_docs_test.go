@@ -165,6 +165,71 @@ func TestGenManTree(t *testing.T) {
 	}
 }
 
+func TestGenManSections(t *testing.T) {
+	c := &cobra.Command{
+		Use: "do",
+		Annotations: map[string]string{
+			ManExitStatusAnnotation:              "1\tthe file could not be read\n2\tthe file is invalid",
+			ManEnvironmentAnnotation:             "DO_CONFIG\tthe configuration file",
+			ManSectionAnnotationPrefix + "NOTES": "Some notes.",
+		},
+		Run: emptyRun,
+	}
+	header := &GenManHeader{
+		ExitStatus: []ManEntry{{"0", "success"}, {"1", "failure"}},
+		Files:      []ManEntry{{"~/.do.yaml", "the default configuration file"}},
+		Bugs:       "Report bugs to the issue tracker.",
+		Sections:   []ManSection{{"AUTHORS", "The authors."}},
+	}
+	buf := new(bytes.Buffer)
+	if err := GenMan(c, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		".SH EXIT STATUS\n.PP\n\\fB0\\fP\n\tsuccess\n\n.PP\n\\fB1\\fP\n\tthe file could not be read\n\n.PP\n\\fB2\\fP\n\tthe file is invalid\n",
+		".SH ENVIRONMENT\n.PP\n\\fBDO\\_CONFIG\\fP\n\tthe configuration file\n",
+		".SH FILES\n.PP\n\\fI\\~/.do.yaml\\fP\n\tthe default configuration file\n",
+		".SH BUGS\n.PP\nReport bugs to the issue tracker.\n",
+		".SH AUTHORS\n.PP\nThe authors.\n\n\n.SH NOTES\n.PP\nSome notes.\n",
+	} {
+		checkStringContains(t, output, expected)
+	}
+}
+
+func TestGenManHelpTopic(t *testing.T) {
+	rootCmd := &cobra.Command{Use: "root", Run: emptyRun}
+	topicCmd := &cobra.Command{Use: "topic", Short: "a help topic", Long: "All about the topic."}
+	rootCmd.AddCommand(topicCmd)
+
+	buf := new(bytes.Buffer)
+	if err := GenMan(rootCmd, &GenManHeader{}, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), `\fBroot\-topic(7)\fP`)
+
+	tmpdir, err := ioutil.TempDir("", "test-gen-man-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %s", err.Error())
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := GenManTree(rootCmd, &GenManHeader{Section: "8"}, tmpdir); err != nil {
+		t.Fatalf("GenManTree failed: %s", err.Error())
+	}
+	page, err := ioutil.ReadFile(filepath.Join(tmpdir, "root-topic.7"))
+	if err != nil {
+		t.Fatalf("Expected file 'root-topic.7' to exist")
+	}
+	output := string(page)
+	checkStringContains(t, output, `.TH ROOT\-TOPIC(7)`)
+	checkStringContains(t, output, topicCmd.Long)
+	checkStringContains(t, output, `\fBroot(8)\fP`)
+	checkStringOmits(t, output, "SYNOPSIS")
+	checkStringOmits(t, output, "OPTIONS")
+}
+
 func assertLineFound(scanner *bufio.Scanner, expectedLine string) error {
 	for scanner.Scan() {
 		line := scanner.Text()
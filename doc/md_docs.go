@@ -15,36 +15,12 @@ package doc
 
 import (
 	"bytes"
-	"fmt"
 	"io"
-	"os"
-	"path/filepath"
-	"sort"
-	"strings"
-	"time"
+	"text/template"
 
 	"github.com/spf13/cobra"
 )
 
-func printOptions(buf *bytes.Buffer, cmd *cobra.Command, name string) error {
-	flags := cmd.NonInheritedFlags()
-	flags.SetOutput(buf)
-	if flags.HasAvailableFlags() {
-		buf.WriteString("### Options\n\n```\n")
-		flags.PrintDefaults()
-		buf.WriteString("```\n\n")
-	}
-
-	parentFlags := cmd.InheritedFlags()
-	parentFlags.SetOutput(buf)
-	if parentFlags.HasAvailableFlags() {
-		buf.WriteString("### Options inherited from parent commands\n\n```\n")
-		parentFlags.PrintDefaults()
-		buf.WriteString("```\n\n")
-	}
-	return nil
-}
-
 // GenMarkdown creates markdown output.
 func GenMarkdown(cmd *cobra.Command, w io.Writer) error {
 	return GenMarkdownCustom(cmd, w, func(s string) string { return s })
@@ -52,108 +28,66 @@ func GenMarkdown(cmd *cobra.Command, w io.Writer) error {
 
 // GenMarkdownCustom creates custom markdown output.
 func GenMarkdownCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) string) error {
-	cmd.InitDefaultHelpCmd()
-	cmd.InitDefaultHelpFlag()
-
-	buf := new(bytes.Buffer)
-	name := cmd.CommandPath()
-
-	short := cmd.Short
-	long := cmd.Long
-	if len(long) == 0 {
-		long = short
-	}
-
-	buf.WriteString("## " + name + "\n\n")
-	buf.WriteString(short + "\n\n")
-	buf.WriteString("### Synopsis\n\n")
-	buf.WriteString(long + "\n\n")
-
-	if cmd.Runnable() {
-		buf.WriteString(fmt.Sprintf("```\n%s\n```\n\n", cmd.UseLine()))
-	}
-
-	if len(cmd.Example) > 0 {
-		buf.WriteString("### Examples\n\n")
-		buf.WriteString(fmt.Sprintf("```\n%s\n```\n\n", cmd.Example))
-	}
+	return GenMarkdownTemplate(cmd, w, defaultMarkdownTemplate, linkHandler)
+}
 
-	if err := printOptions(buf, cmd, name); err != nil {
-		return err
-	}
-	if hasSeeAlso(cmd) {
-		buf.WriteString("### SEE ALSO\n\n")
-		if cmd.HasParent() {
-			parent := cmd.Parent()
-			pname := parent.CommandPath()
-			link := pname + ".md"
-			link = strings.Replace(link, " ", "_", -1)
-			buf.WriteString(fmt.Sprintf("* [%s](%s)\t - %s\n", pname, linkHandler(link), parent.Short))
-			cmd.VisitParents(func(c *cobra.Command) {
-				if c.DisableAutoGenTag {
-					cmd.DisableAutoGenTag = c.DisableAutoGenTag
-				}
-			})
-		}
+// GenMarkdownTemplate creates markdown output with tmpl, which is executed
+// with a *DocData. DefaultMarkdownTemplate is the template of GenMarkdown.
+// The linkHandler receives the filename of the page of the commands cmd
+// refers to (e.g., "root_sub.md").
+func GenMarkdownTemplate(cmd *cobra.Command, w io.Writer, tmpl *template.Template, linkHandler func(string) string) error {
+	return tmpl.Execute(w, newMarkdownDocData(cmd, defaultNaming, DefaultTreeFilter, linkHandler))
+}
 
-		children := cmd.Commands()
-		sort.Sort(byName(children))
+func newMarkdownDocData(cmd *cobra.Command, naming NamingStrategy, filter func(*cobra.Command) bool, linkHandler func(string) string) *DocData {
+	return newDocData(cmd, naming, filter, func(name, ref string) string { return linkHandler(ref + ".md") })
+}
 
-		for _, child := range children {
-			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
-				continue
-			}
-			cname := name + " " + child.Name()
-			link := cname + ".md"
-			link = strings.Replace(link, " ", "_", -1)
-			buf.WriteString(fmt.Sprintf("* [%s](%s)\t - %s\n", cname, linkHandler(link), child.Short))
-		}
-		buf.WriteString("\n")
-	}
-	if !cmd.DisableAutoGenTag {
-		buf.WriteString("###### Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006") + "\n")
-	}
-	_, err := buf.WriteTo(w)
-	return err
+// genMarkdown writes the markdown documentation of cmd, without the auto
+// generated tag.
+func genMarkdown(buf *bytes.Buffer, cmd *cobra.Command, linkHandler func(string) string) error {
+	data := newMarkdownDocData(cmd, defaultNaming, DefaultTreeFilter, linkHandler)
+	data.AutoGenTag = ""
+	return defaultMarkdownTemplate.Execute(buf, data)
 }
 
 // GenMarkdownTree will generate a markdown page for this command and all
-// descendants in the directory given. The header may be nil.
-// This function may not work correctly if your command names have `-` in them.
-// If you have `cmd` with two subcmds, `sub` and `sub-third`,
-// and `sub` has a subcommand called `third`, it is undefined which
-// help output will be in the file `cmd-sub-third.1`.
+// descendants in the directory given. It returns an error if two commands
+// have the same file name, such as `cmd sub-third` and `cmd sub third`,
+// see GenMarkdownTreeFromOpts to name the files differently.
 func GenMarkdownTree(cmd *cobra.Command, dir string) error {
 	identity := func(s string) string { return s }
 	emptyStr := func(s string) string { return "" }
 	return GenMarkdownTreeCustom(cmd, dir, emptyStr, identity)
 }
 
-// GenMarkdownTreeCustom is the the same as GenMarkdownTree, but
+// GenMarkdownTreeCustom is the same as GenMarkdownTree, but
 // with custom filePrepender and linkHandler.
 func GenMarkdownTreeCustom(cmd *cobra.Command, dir string, filePrepender, linkHandler func(string) string) error {
-	for _, c := range cmd.Commands() {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-			continue
-		}
-		if err := GenMarkdownTreeCustom(c, dir, filePrepender, linkHandler); err != nil {
-			return err
-		}
-	}
+	return genMarkdownTree(cmd, TreeOptions{Dir: dir}, filePrepender, defaultMarkdownTemplate, linkHandler)
+}
+
+// GenMarkdownTreeTemplate is the same as GenMarkdownTree, but
+// executes tmpl for each page, like GenMarkdownTemplate.
+func GenMarkdownTreeTemplate(cmd *cobra.Command, dir string, tmpl *template.Template, linkHandler func(string) string) error {
+	emptyStr := func(s string) string { return "" }
+	return genMarkdownTree(cmd, TreeOptions{Dir: dir}, emptyStr, tmpl, linkHandler)
+}
 
-	basename := strings.Replace(cmd.CommandPath(), " ", "_", -1) + ".md"
-	filename := filepath.Join(dir, basename)
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+// GenMarkdownTreeFromOpts is the same as GenMarkdownTree, but
+// with the commands, the names of their pages and the output chosen by opts.
+func GenMarkdownTreeFromOpts(cmd *cobra.Command, opts TreeOptions) error {
+	identity := func(s string) string { return s }
+	emptyStr := func(s string) string { return "" }
+	return genMarkdownTree(cmd, opts, emptyStr, defaultMarkdownTemplate, identity)
+}
 
-	if _, err := io.WriteString(f, filePrepender(filename)); err != nil {
-		return err
-	}
-	if err := GenMarkdownCustom(cmd, f, linkHandler); err != nil {
-		return err
-	}
-	return nil
+func genMarkdownTree(cmd *cobra.Command, opts TreeOptions, filePrepender func(string) string, tmpl *template.Template, linkHandler func(string) string) error {
+	opts = opts.withDefaults(defaultNaming, DefaultTreeFilter)
+	return genTree(cmd, opts, defaultNaming, DefaultTreeFilter, ".md", func(c *cobra.Command, filename string, w io.Writer) error {
+		if _, err := io.WriteString(w, filePrepender(filename)); err != nil {
+			return err
+		}
+		return tmpl.Execute(w, newMarkdownDocData(c, opts.Naming, opts.Filter, linkHandler))
+	})
 }
@@ -96,3 +96,17 @@ func BenchmarkGenMarkdownToFile(b *testing.B) {
 		}
 	}
 }
+
+func TestGenMdEnumFlag(t *testing.T) {
+	c := &cobra.Command{Use: "render", Run: emptyRun}
+	c.Flags().String("output", "json", "output format")
+	if err := c.MarkFlagEnum("output", "json\tJSON document", "yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdown(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "output format (one of: json, yaml)")
+}
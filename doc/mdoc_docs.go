@@ -0,0 +1,293 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// mdocEscaper escapes the backslashes of text, which would start roff escapes.
+var mdocEscaper = strings.NewReplacer("\\", "\\e")
+
+// mdocArg returns s as a single quoted macro argument, which is not parsed
+// for macros.
+func mdocArg(s string) string {
+	return `"` + strings.Replace(mdocEscaper.Replace(s), `"`, `\(dq`, -1) + `"`
+}
+
+// mdocLine returns line as a text line, escaping the control characters
+// starting it.
+func mdocLine(line string) string {
+	line = mdocEscaper.Replace(line)
+	if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+		return `\&` + line
+	}
+	return line
+}
+
+// mdocText writes text as filled text lines, with a .Pp macro between its
+// paragraphs. The indentation and trailing spaces of the lines are removed.
+func mdocText(buf *bytes.Buffer, text string) {
+	paragraph := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			paragraph = true
+			continue
+		}
+		if paragraph {
+			buf.WriteString(".Pp\n")
+			paragraph = false
+		}
+		buf.WriteString(mdocLine(line) + "\n")
+	}
+}
+
+// mdocLiteral writes text as a literal display.
+func mdocLiteral(buf *bytes.Buffer, text string) {
+	buf.WriteString(".Bd -literal -offset indent\n")
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		buf.WriteString(mdocLine(strings.TrimRight(line, " \t")) + "\n")
+	}
+	buf.WriteString(".Ed\n")
+}
+
+// mdocUseArgs returns the arguments of the use line of cmd after its name,
+// as macros: "[file...]" is ".Op Ar file ...", "<name>" is ".Ar name" and
+// the "[flags]" cobra adds is ".Op Ar options".
+func mdocUseArgs(cmd *cobra.Command) []string {
+	var args []string
+	line := strings.TrimPrefix(cmd.UseLine(), cmd.CommandPath())
+	for _, field := range splitUseArgs(line) {
+		if field == "[flags]" {
+			// The flags are listed in the DESCRIPTION section
+			args = append(args, ".Op Ar options")
+			continue
+		}
+		ellipsis := ""
+		if strings.HasSuffix(field, "...") {
+			field, ellipsis = strings.TrimSuffix(field, "..."), " ..."
+		}
+		macro := ".Ar "
+		if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+			macro, field = ".Op Ar ", field[1:len(field)-1]
+			if strings.HasSuffix(field, "...") {
+				field, ellipsis = strings.TrimSuffix(field, "..."), " ..."
+			}
+		}
+		field = strings.TrimSuffix(strings.TrimPrefix(field, "<"), ">")
+		if field == "" {
+			continue
+		}
+		args = append(args, macro+mdocArg(field)+ellipsis)
+	}
+	return args
+}
+
+// splitUseArgs splits the arguments of a use line on the spaces which are
+// not within brackets, so that "[string to echo]" is a single argument.
+func splitUseArgs(line string) []string {
+	var args []string
+	depth, start := 0, -1
+	for i, r := range line {
+		switch {
+		case r == '[' || r == '<':
+			depth++
+		case (r == ']' || r == '>') && depth > 0:
+			depth--
+		case r == ' ' && depth == 0:
+			if start >= 0 {
+				args = append(args, line[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		args = append(args, line[start:])
+	}
+	return args
+}
+
+// mdocPrintFlags writes the list of the available flags.
+func mdocPrintFlags(buf *bytes.Buffer, flags *pflag.FlagSet, intro string) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	buf.WriteString(".Pp\n" + intro + "\n.Bl -tag -width Ds\n")
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if len(flag.Deprecated) > 0 || flag.Hidden {
+			return
+		}
+		varname, usage := pflag.UnquoteUsage(helpFlag(flag))
+		item := ".It Fl -" + flag.Name
+		if len(flag.Shorthand) > 0 && len(flag.ShorthandDeprecated) == 0 {
+			item = ".It Fl " + flag.Shorthand + " , Fl -" + flag.Name
+		}
+		if varname != "" {
+			if len(flag.NoOptDefVal) > 0 {
+				item += " Ns Op = Ns Ar " + mdocArg(varname)
+			} else {
+				item += " Ar " + mdocArg(varname)
+			}
+		}
+		buf.WriteString(item + "\n")
+		mdocText(buf, usage)
+		// The flags without value, such as the booleans, are off by default
+		if varname != "" && flag.DefValue != "" && flag.DefValue != "[]" {
+			buf.WriteString("The default is\n.Ql " + mdocArg(flag.DefValue) + " .\n")
+		}
+	})
+	buf.WriteString(".El\n")
+}
+
+// mdocPrintEntries writes the section title listing entries, their names
+// formatted with the macro nameMacro, if any.
+func mdocPrintEntries(buf *bytes.Buffer, title string, entries []ManEntry, nameMacro string) {
+	if len(entries) == 0 {
+		return
+	}
+	buf.WriteString(".Sh " + title + "\n.Bl -tag -width Ds\n")
+	for _, entry := range entries {
+		buf.WriteString(".It " + nameMacro + mdocArg(entry.Name) + "\n")
+		mdocText(buf, entry.Description)
+	}
+	buf.WriteString(".El\n")
+}
+
+// mdocXref is a page of the SEE ALSO section.
+type mdocXref struct {
+	name    string
+	section string
+}
+
+// GenMdoc will generate a man page for the given command with the semantic
+// mdoc(7) macros, instead of the man(7) macros GenMan uses, and write it to w.
+// The header argument may be nil. The Source and Manual of the header are not
+// used: the operating system and the volume of the section are shown instead.
+func GenMdoc(cmd *cobra.Command, header *GenManHeader, w io.Writer) error {
+	if header == nil {
+		header = &GenManHeader{}
+	}
+	// something like `rootcmd-subcmd1-subcmd2`
+	dashCommandName := strings.Replace(cmd.CommandPath(), " ", "-", -1)
+	if header.Title == "" {
+		header.Title = strings.ToUpper(dashCommandName)
+	}
+	if err := fillHeader(header, cmd.CommandPath()); err != nil {
+		return err
+	}
+
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+	cmd.VisitParents(func(c *cobra.Command) {
+		if c.DisableAutoGenTag {
+			cmd.DisableAutoGenTag = c.DisableAutoGenTag
+		}
+	})
+
+	buf := new(bytes.Buffer)
+	if !cmd.DisableAutoGenTag {
+		buf.WriteString(`.\" Auto generated by spf13/cobra` + "\n")
+	}
+	buf.WriteString(".Dd " + header.Date.Format("January 2, 2006") + "\n")
+	buf.WriteString(fmt.Sprintf(".Dt %s %s\n", header.Title, manPageSection(header, cmd)))
+	buf.WriteString(".Os\n")
+
+	buf.WriteString(".Sh NAME\n")
+	buf.WriteString(".Nm " + dashCommandName + "\n")
+	buf.WriteString(".Nd " + mdocArg(strings.TrimSuffix(strings.TrimSpace(cmd.Short), ".")) + "\n")
+	if !isManHelpTopic(cmd) {
+		buf.WriteString(".Sh SYNOPSIS\n")
+		buf.WriteString(".Nm " + cmd.CommandPath() + "\n")
+		for _, arg := range mdocUseArgs(cmd) {
+			buf.WriteString(arg + "\n")
+		}
+	}
+
+	buf.WriteString(".Sh DESCRIPTION\n")
+	description := cmd.Long
+	if len(description) == 0 {
+		description = cmd.Short
+	}
+	mdocText(buf, description)
+	if !isManHelpTopic(cmd) {
+		mdocPrintFlags(buf, cmd.NonInheritedFlags(), "The options are as follows:")
+		mdocPrintFlags(buf, cmd.InheritedFlags(), "The options inherited from parent commands are as follows:")
+	}
+
+	sections := collectManSections(header, cmd)
+	mdocPrintEntries(buf, "ENVIRONMENT", sections.environment, "Ev ")
+	mdocPrintEntries(buf, "FILES", sections.files, "Pa ")
+	mdocPrintEntries(buf, "EXIT STATUS", sections.exitStatus, "")
+	if len(cmd.Example) > 0 {
+		buf.WriteString(".Sh EXAMPLES\n")
+		mdocLiteral(buf, cmd.Example)
+	}
+
+	if hasSeeAlso(cmd, DefaultTreeFilter) || hasManHelpTopics(cmd) {
+		var xrefs []mdocXref
+		if cmd.HasParent() {
+			parent := cmd.Parent()
+			xrefs = append(xrefs, mdocXref{strings.Replace(parent.CommandPath(), " ", "-", -1), manPageSection(header, parent)})
+		}
+		for _, c := range cmd.Commands() {
+			if (!c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand()) && !isManHelpTopic(c) {
+				continue
+			}
+			xrefs = append(xrefs, mdocXref{dashCommandName + "-" + c.Name(), manPageSection(header, c)})
+		}
+		// mdoc lists the pages by section, then by name
+		sort.SliceStable(xrefs, func(i, j int) bool {
+			if xrefs[i].section != xrefs[j].section {
+				return xrefs[i].section < xrefs[j].section
+			}
+			return xrefs[i].name < xrefs[j].name
+		})
+		buf.WriteString(".Sh SEE ALSO\n")
+		for i, xref := range xrefs {
+			line := fmt.Sprintf(".Xr %s %s", xref.name, xref.section)
+			if i < len(xrefs)-1 {
+				line += " ,"
+			}
+			buf.WriteString(line + "\n")
+		}
+	}
+
+	if sections.bugs != "" {
+		buf.WriteString(".Sh BUGS\n")
+		mdocText(buf, sections.bugs)
+	}
+	for _, section := range sections.custom {
+		buf.WriteString(".Sh " + strings.ToUpper(section.Name) + "\n")
+		mdocText(buf, section.Content)
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// GenMdocTree will generate an mdoc(7) man page for this command and all
+// descendants in the directory given, named like the pages of GenManTree.
+// The header may be nil.
+func GenMdocTree(cmd *cobra.Command, header *GenManHeader, dir string) error {
+	return GenMdocTreeFromOpts(cmd, GenManTreeOptions{
+		Header:           header,
+		Path:             dir,
+		CommandSeparator: "-",
+	})
+}
+
+// GenMdocTreeFromOpts is the same as GenManTreeFromOpts, but generates
+// mdoc(7) man pages, like GenMdoc.
+func GenMdocTreeFromOpts(cmd *cobra.Command, opts GenManTreeOptions) error {
+	return genManTree(cmd, opts, "", GenMdoc)
+}
@@ -0,0 +1,111 @@
+package doc
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenMdoc(t *testing.T) {
+	date := time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)
+	header := &GenManHeader{Date: &date}
+	buf := new(bytes.Buffer)
+	if err := GenMdoc(echoCmd, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, ".Dd March 4, 2020\n.Dt ROOT-ECHO 1\n.Os\n")
+	checkStringContains(t, output, ".Sh NAME\n.Nm root-echo\n.Nd \"Echo anything to the screen\"\n")
+	checkStringContains(t, output, ".Sh SYNOPSIS\n.Nm root echo\n.Op Ar \"string to echo\"\n.Op Ar options\n")
+	checkStringContains(t, output, ".It Fl b , Fl -boolone\n")
+	checkStringContains(t, output, ".It Fl s , Fl -strone Ar \"string\"\n")
+	checkStringContains(t, output, ".It Fl r , Fl -rootflag Ar \"string\"\n")
+	checkStringContains(t, output, ".Sh EXAMPLES\n.Bd -literal -offset indent\n"+echoCmd.Example+"\n.Ed\n")
+	checkStringContains(t, output, ".Sh SEE ALSO\n.Xr root 1 ,\n.Xr root-echo-echosub 1 ,\n.Xr root-echo-times 1\n")
+	checkStringOmits(t, output, "deprecated")
+}
+
+func TestGenMdocEscaping(t *testing.T) {
+	cmd := &cobra.Command{
+		Use:   "root <file> [dir...]",
+		Short: `Print "things" to C:\tmp.`,
+		Long:  "First paragraph.\n\n.starts with a dot\n  'starts with a quote",
+		Run:   emptyRun,
+	}
+	buf := new(bytes.Buffer)
+	if err := GenMdoc(cmd, nil, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, `.Nd "Print \(dqthings\(dq to C:\etmp"`+"\n")
+	checkStringContains(t, output, ".Nm root\n.Ar \"file\"\n.Op Ar \"dir\" ...\n")
+	checkStringContains(t, output, "First paragraph.\n.Pp\n\\&.starts with a dot\n\\&'starts with a quote\n")
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if line == "" || strings.HasSuffix(line, " ") {
+			t.Errorf("Unexpected blank line or trailing space in %q", line)
+		}
+	}
+}
+
+func TestGenMdocSections(t *testing.T) {
+	root := &cobra.Command{Use: "root", Short: "Root", Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "topic", Short: "A topic"}, &cobra.Command{Use: "sub", Short: "Sub", Run: emptyRun})
+	root.Annotations = map[string]string{ManEnvironmentAnnotation: "ROOT_CONFIG\tThe configuration."}
+	header := &GenManHeader{
+		ExitStatus: []ManEntry{{Name: "0", Description: "Success."}},
+		Bugs:       "Many.",
+	}
+	buf := new(bytes.Buffer)
+	if err := GenMdoc(root, header, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, ".Sh ENVIRONMENT\n.Bl -tag -width Ds\n.It Ev \"ROOT_CONFIG\"\nThe configuration.\n.El\n")
+	checkStringContains(t, output, ".Sh EXIT STATUS\n.Bl -tag -width Ds\n.It \"0\"\nSuccess.\n.El\n")
+	checkStringContains(t, output, ".Sh SEE ALSO\n.Xr root-sub 1 ,\n.Xr root-topic 7\n.Sh BUGS\nMany.\n")
+
+	files := cobra.NewMemFS()
+	header = &GenManHeader{}
+	if err := GenMdocTreeFromOpts(root, GenManTreeOptions{Header: header, CommandSeparator: "-", FS: files}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fileNames(files); got != "root-sub.1 root-topic.7 root.1" {
+		t.Errorf("Unexpected files %q", got)
+	}
+	topic := readFile(t, files, "root-topic.7")
+	checkStringContains(t, topic, ".Dt ROOT-TOPIC 7\n")
+	checkStringOmits(t, topic, ".Sh SYNOPSIS")
+}
+
+func TestGenMdocLint(t *testing.T) {
+	mandoc, err := exec.LookPath("mandoc")
+	if err != nil {
+		t.Skip("mandoc is not installed")
+	}
+	escaping := &cobra.Command{
+		Use:     "root <file> [dir...]",
+		Short:   `Print "things" to C:\tmp.`,
+		Long:    "First paragraph.\n\n.starts with a dot\n  'starts with a quote",
+		Example: "root file dir",
+		Run:     emptyRun,
+	}
+	for _, cmd := range []*cobra.Command{rootCmd, echoCmd, escaping} {
+		buf := new(bytes.Buffer)
+		if err := GenMdoc(cmd, nil, buf); err != nil {
+			t.Fatal(err)
+		}
+		lint := exec.Command(mandoc, "-Tlint", "-W", "warning")
+		lint.Stdin = buf
+		output, err := lint.CombinedOutput()
+		if err != nil || len(output) > 0 {
+			t.Errorf("mandoc reported problems with the page of %q: %v\n%s", cmd.CommandPath(), err, output)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ReferenceOptions are the options of GenMarkdownReference and GenReSTReference.
+type ReferenceOptions struct {
+	// Start is the path of the first command of the reference, relative to
+	// the command given (e.g., "sub subsub"). If empty, the reference starts
+	// with the command given.
+	Start string
+	// MaxDepth is the number of levels of commands in the reference: 1 only
+	// documents the first command, 2 its subcommands as well, and so on.
+	// If 0, all the descendants of the first command are documented.
+	MaxDepth int
+}
+
+// referenceEntry is a command of a reference, at depth 0 for the first command.
+type referenceEntry struct {
+	cmd   *cobra.Command
+	depth int
+}
+
+// referenceAnchor returns the anchor of the section of cmd in a reference,
+// which is also the basename of its page in a tree (e.g., "root_sub").
+func referenceAnchor(cmd *cobra.Command) string {
+	return strings.Replace(cmd.CommandPath(), " ", "_", -1)
+}
+
+// referenceEntries returns the commands of the reference of cmd, in the
+// order they are documented, and the anchors of their sections.
+func referenceEntries(cmd *cobra.Command, opts ReferenceOptions) ([]referenceEntry, map[string]bool, error) {
+	start := cmd
+	if opts.Start != "" {
+		found, args, err := cmd.Find(strings.Fields(opts.Start))
+		if err != nil || len(args) > 0 {
+			return nil, nil, fmt.Errorf("unknown command %q for %q", opts.Start, cmd.CommandPath())
+		}
+		start = found
+	}
+
+	var entries []referenceEntry
+	anchors := make(map[string]bool)
+	var visit func(c *cobra.Command, depth int)
+	visit = func(c *cobra.Command, depth int) {
+		entries = append(entries, referenceEntry{cmd: c, depth: depth})
+		anchors[referenceAnchor(c)] = true
+		if opts.MaxDepth > 0 && depth+1 >= opts.MaxDepth {
+			return
+		}
+		children := c.Commands()
+		sort.Sort(byName(children))
+		for _, child := range children {
+			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
+				continue
+			}
+			visit(child, depth+1)
+		}
+	}
+	visit(start, 0)
+	return entries, anchors, nil
+}
+
+// GenMarkdownReference creates a single markdown document of this command
+// and its descendants: a nested table of contents, followed by the
+// documentation of each command, as written by GenMarkdown, with an anchor
+// named like its page in GenMarkdownTree (e.g., "root_sub"). The commands link
+// to each other within the document; the commands outside of the document are
+// linked to their page in GenMarkdownTree.
+func GenMarkdownReference(cmd *cobra.Command, w io.Writer, opts ReferenceOptions) error {
+	entries, anchors, err := referenceEntries(cmd, opts)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	for _, entry := range entries {
+		indent := strings.Repeat("  ", entry.depth)
+		buf.WriteString(fmt.Sprintf("%s- [%s](#%s)\n", indent, entry.cmd.CommandPath(), referenceAnchor(entry.cmd)))
+	}
+	buf.WriteString("\n")
+
+	linkHandler := func(link string) string {
+		if anchor := strings.TrimSuffix(link, ".md"); anchors[anchor] {
+			return "#" + anchor
+		}
+		return link
+	}
+	for _, entry := range entries {
+		buf.WriteString(fmt.Sprintf("<a id=\"%s\"></a>\n\n", referenceAnchor(entry.cmd)))
+		if err := genMarkdown(buf, entry.cmd, linkHandler); err != nil {
+			return err
+		}
+	}
+	if !entries[0].cmd.DisableAutoGenTag {
+		buf.WriteString("###### Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006") + "\n")
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// GenReSTReference creates a single reStructured Text document of this
+// command and its descendants: a nested table of contents, followed by the
+// documentation of each command, as written by GenReST, with a target named
+// like its page in GenReSTTree (e.g., "root_sub"). The commands link to each
+// other within the document; the commands outside of the document are linked
+// to their page in GenReSTTree.
+func GenReSTReference(cmd *cobra.Command, w io.Writer, opts ReferenceOptions) error {
+	entries, anchors, err := referenceEntries(cmd, opts)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	for _, entry := range entries {
+		indent := strings.Repeat("  ", entry.depth)
+		buf.WriteString(fmt.Sprintf("%s* `%s <%s_>`_\n\n", indent, entry.cmd.CommandPath(), referenceAnchor(entry.cmd)))
+	}
+
+	linkHandler := func(name, ref string) string {
+		if anchors[ref] {
+			return fmt.Sprintf("`%s <%s_>`_", name, ref)
+		}
+		return defaultLinkHandler(name, ref)
+	}
+	for _, entry := range entries {
+		if err := genReST(buf, entry.cmd, linkHandler); err != nil {
+			return err
+		}
+	}
+	if !entries[0].cmd.DisableAutoGenTag {
+		buf.WriteString("*Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006") + "*\n")
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,53 @@
+package doc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenMarkdownReference(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownReference(rootCmd, buf, ReferenceOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "- [root](#root)\n  - [root echo](#root_echo)\n    - [root echo echosub](#root_echo_echosub)\n")
+	checkStringContains(t, output, "<a id=\"root_echo\"></a>\n\n## root echo\n")
+	checkStringContains(t, output, "* [root echo times](#root_echo_times)")
+	checkStringContains(t, output, "* [root](#root)")
+	checkStringContains(t, output, echoSubCmd.Long)
+	checkStringOmits(t, output, deprecatedCmd.Short)
+	checkStringOmits(t, output, ".md)")
+}
+
+func TestGenMarkdownReferenceSubtree(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownReference(rootCmd, buf, ReferenceOptions{Start: "echo", MaxDepth: 1}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "## root echo\n")
+	checkStringContains(t, output, "* [root](root.md)")
+	checkStringContains(t, output, "* [root echo times](root_echo_times.md)")
+	checkStringOmits(t, output, "## root echo times")
+	checkStringOmits(t, output, echoSubCmd.Long)
+
+	if err := GenMarkdownReference(rootCmd, buf, ReferenceOptions{Start: "unknown"}); err == nil {
+		t.Error("Expected an error for an unknown start command")
+	}
+}
+
+func TestGenReSTReference(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenReSTReference(rootCmd, buf, ReferenceOptions{Start: "echo", MaxDepth: 2}); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "* `root echo <root_echo_>`_\n\n  * `root echo echosub <root_echo_echosub_>`_\n\n")
+	checkStringContains(t, output, ".. _root_echo_times:\n\nroot echo times\n")
+	checkStringContains(t, output, "* `root echo times <root_echo_times_>`_")
+	checkStringContains(t, output, "* `root <root.rst>`_")
+}
@@ -17,36 +17,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
-	"sort"
-	"strings"
-	"time"
+	"text/template"
 
 	"github.com/spf13/cobra"
 )
 
-func printOptionsReST(buf *bytes.Buffer, cmd *cobra.Command, name string) error {
-	flags := cmd.NonInheritedFlags()
-	flags.SetOutput(buf)
-	if flags.HasAvailableFlags() {
-		buf.WriteString("Options\n")
-		buf.WriteString("~~~~~~~\n\n::\n\n")
-		flags.PrintDefaults()
-		buf.WriteString("\n")
-	}
-
-	parentFlags := cmd.InheritedFlags()
-	parentFlags.SetOutput(buf)
-	if parentFlags.HasAvailableFlags() {
-		buf.WriteString("Options inherited from parent commands\n")
-		buf.WriteString("~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~\n\n::\n\n")
-		parentFlags.PrintDefaults()
-		buf.WriteString("\n")
-	}
-	return nil
-}
-
 // linkHandler for default ReST hyperlink markup
 func defaultLinkHandler(name, ref string) string {
 	return fmt.Sprintf("`%s <%s.rst>`_", name, ref)
@@ -59,113 +34,60 @@ func GenReST(cmd *cobra.Command, w io.Writer) error {
 
 // GenReSTCustom creates custom reStructured Text output.
 func GenReSTCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string, string) string) error {
-	cmd.InitDefaultHelpCmd()
-	cmd.InitDefaultHelpFlag()
-
-	buf := new(bytes.Buffer)
-	name := cmd.CommandPath()
-
-	short := cmd.Short
-	long := cmd.Long
-	if len(long) == 0 {
-		long = short
-	}
-	ref := strings.Replace(name, " ", "_", -1)
-
-	buf.WriteString(".. _" + ref + ":\n\n")
-	buf.WriteString(name + "\n")
-	buf.WriteString(strings.Repeat("-", len(name)) + "\n\n")
-	buf.WriteString(short + "\n\n")
-	buf.WriteString("Synopsis\n")
-	buf.WriteString("~~~~~~~~\n\n")
-	buf.WriteString("\n" + long + "\n\n")
-
-	if cmd.Runnable() {
-		buf.WriteString(fmt.Sprintf("::\n\n  %s\n\n", cmd.UseLine()))
-	}
-
-	if len(cmd.Example) > 0 {
-		buf.WriteString("Examples\n")
-		buf.WriteString("~~~~~~~~\n\n")
-		buf.WriteString(fmt.Sprintf("::\n\n%s\n\n", indentString(cmd.Example, "  ")))
-	}
-
-	if err := printOptionsReST(buf, cmd, name); err != nil {
-		return err
-	}
-	if hasSeeAlso(cmd) {
-		buf.WriteString("SEE ALSO\n")
-		buf.WriteString("~~~~~~~~\n\n")
-		if cmd.HasParent() {
-			parent := cmd.Parent()
-			pname := parent.CommandPath()
-			ref = strings.Replace(pname, " ", "_", -1)
-			buf.WriteString(fmt.Sprintf("* %s \t - %s\n", linkHandler(pname, ref), parent.Short))
-			cmd.VisitParents(func(c *cobra.Command) {
-				if c.DisableAutoGenTag {
-					cmd.DisableAutoGenTag = c.DisableAutoGenTag
-				}
-			})
-		}
+	return GenReSTTemplate(cmd, w, defaultReSTTemplate, linkHandler)
+}
 
-		children := cmd.Commands()
-		sort.Sort(byName(children))
+// GenReSTTemplate creates reStructured Text output with tmpl, which is
+// executed with a *DocData. DefaultReSTTemplate is the template of GenReST.
+func GenReSTTemplate(cmd *cobra.Command, w io.Writer, tmpl *template.Template, linkHandler func(string, string) string) error {
+	return tmpl.Execute(w, newDocData(cmd, defaultNaming, DefaultTreeFilter, linkHandler))
+}
 
-		for _, child := range children {
-			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
-				continue
-			}
-			cname := name + " " + child.Name()
-			ref = strings.Replace(cname, " ", "_", -1)
-			buf.WriteString(fmt.Sprintf("* %s \t - %s\n", linkHandler(cname, ref), child.Short))
-		}
-		buf.WriteString("\n")
-	}
-	if !cmd.DisableAutoGenTag {
-		buf.WriteString("*Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006") + "*\n")
-	}
-	_, err := buf.WriteTo(w)
-	return err
+// genReST writes the reStructured Text documentation of cmd, without the
+// auto generated tag.
+func genReST(buf *bytes.Buffer, cmd *cobra.Command, linkHandler func(string, string) string) error {
+	data := newDocData(cmd, defaultNaming, DefaultTreeFilter, linkHandler)
+	data.AutoGenTag = ""
+	return defaultReSTTemplate.Execute(buf, data)
 }
 
 // GenReSTTree will generate a ReST page for this command and all
-// descendants in the directory given.
-// This function may not work correctly if your command names have `-` in them.
-// If you have `cmd` with two subcmds, `sub` and `sub-third`,
-// and `sub` has a subcommand called `third`, it is undefined which
-// help output will be in the file `cmd-sub-third.1`.
+// descendants in the directory given. It returns an error if two commands
+// have the same file name, such as `cmd sub-third` and `cmd sub third`,
+// see GenReSTTreeFromOpts to name the files differently.
 func GenReSTTree(cmd *cobra.Command, dir string) error {
 	emptyStr := func(s string) string { return "" }
 	return GenReSTTreeCustom(cmd, dir, emptyStr, defaultLinkHandler)
 }
 
-// GenReSTTreeCustom is the the same as GenReSTTree, but
+// GenReSTTreeCustom is the same as GenReSTTree, but
 // with custom filePrepender and linkHandler.
 func GenReSTTreeCustom(cmd *cobra.Command, dir string, filePrepender func(string) string, linkHandler func(string, string) string) error {
-	for _, c := range cmd.Commands() {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-			continue
-		}
-		if err := GenReSTTreeCustom(c, dir, filePrepender, linkHandler); err != nil {
-			return err
-		}
-	}
+	return genReSTTree(cmd, TreeOptions{Dir: dir}, filePrepender, defaultReSTTemplate, linkHandler)
+}
 
-	basename := strings.Replace(cmd.CommandPath(), " ", "_", -1) + ".rst"
-	filename := filepath.Join(dir, basename)
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+// GenReSTTreeTemplate is the same as GenReSTTree, but
+// executes tmpl for each page, like GenReSTTemplate.
+func GenReSTTreeTemplate(cmd *cobra.Command, dir string, tmpl *template.Template, linkHandler func(string, string) string) error {
+	emptyStr := func(s string) string { return "" }
+	return genReSTTree(cmd, TreeOptions{Dir: dir}, emptyStr, tmpl, linkHandler)
+}
 
-	if _, err := io.WriteString(f, filePrepender(filename)); err != nil {
-		return err
-	}
-	if err := GenReSTCustom(cmd, f, linkHandler); err != nil {
-		return err
-	}
-	return nil
+// GenReSTTreeFromOpts is the same as GenReSTTree, but
+// with the commands, the names of their pages and the output chosen by opts.
+func GenReSTTreeFromOpts(cmd *cobra.Command, opts TreeOptions) error {
+	emptyStr := func(s string) string { return "" }
+	return genReSTTree(cmd, opts, emptyStr, defaultReSTTemplate, defaultLinkHandler)
+}
+
+func genReSTTree(cmd *cobra.Command, opts TreeOptions, filePrepender func(string) string, tmpl *template.Template, linkHandler func(string, string) string) error {
+	opts = opts.withDefaults(defaultNaming, DefaultTreeFilter)
+	return genTree(cmd, opts, defaultNaming, DefaultTreeFilter, ".rst", func(c *cobra.Command, filename string, w io.Writer) error {
+		if _, err := io.WriteString(w, filePrepender(filename)); err != nil {
+			return err
+		}
+		return tmpl.Execute(w, newDocData(c, opts.Naming, opts.Filter, linkHandler))
+	})
 }
 
 // adapted from: https://github.com/kr/text/blob/main/indent.go
@@ -0,0 +1,270 @@
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// SitePage is the page of a command in a tree generated for a static site.
+type SitePage struct {
+	Command *cobra.Command
+	// Ref is the name of the file of the page, without its extension
+	// (e.g., "root_sub").
+	Ref string
+	// Weight is the position of the page in the tree, starting at 1: the
+	// page of a command comes before the pages of its subcommands, which are
+	// in the order of the subcommands.
+	Weight int
+	// Parent is the page of the parent of the command, nil for the first
+	// page of the tree.
+	Parent *SitePage
+	// Children are the pages of the subcommands of the command in the tree.
+	Children []*SitePage
+}
+
+// SitePreset adapts the markdown pages of GenMarkdownSiteTree to a static
+// site generator.
+type SitePreset struct {
+	// FrontMatter returns the front matter written at the start of a page.
+	FrontMatter func(page *SitePage) (string, error)
+	// Link returns the link to a page from the other pages.
+	Link func(page *SitePage) string
+	// Body, if not nil, returns the content written after the front matter
+	// given the markdown of the page, e.g. to escape it for a template engine.
+	Body func(page *SitePage, markdown string) string
+	// Index, if not nil, returns the name and the content of a file written
+	// next to the pages, such as a sidebar, given the first page of the tree.
+	Index func(root *SitePage) (string, []byte, error)
+}
+
+// frontMatter returns the YAML front matter holding fields.
+func frontMatter(fields yaml.MapSlice) (string, error) {
+	out, err := yaml.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return "---\n" + string(out) + "---\n\n", nil
+}
+
+// siteURL returns the URL of page under baseURL, a directory of the site.
+func siteURL(baseURL string, page *SitePage) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + page.Ref + "/"
+}
+
+// HugoPreset returns the preset of Hugo. The pages are served at baseURL
+// followed by their Ref (e.g., "/commands/root_sub/"), and are entries of
+// the menu named menu, nested like the commands.
+func HugoPreset(baseURL, menu string) SitePreset {
+	return SitePreset{
+		FrontMatter: func(page *SitePage) (string, error) {
+			entry := yaml.MapSlice{
+				{Key: "identifier", Value: page.Ref},
+				{Key: "name", Value: page.Command.Name()},
+			}
+			if page.Parent != nil {
+				entry = append(entry, yaml.MapItem{Key: "parent", Value: page.Parent.Ref})
+			}
+			entry = append(entry, yaml.MapItem{Key: "weight", Value: page.Weight})
+			return frontMatter(yaml.MapSlice{
+				{Key: "title", Value: page.Command.CommandPath()},
+				{Key: "linkTitle", Value: page.Command.Name()},
+				{Key: "slug", Value: page.Ref},
+				{Key: "url", Value: siteURL(baseURL, page)},
+				{Key: "weight", Value: page.Weight},
+				{Key: "menu", Value: yaml.MapSlice{{Key: menu, Value: entry}}},
+			})
+		},
+		Link: func(page *SitePage) string {
+			return siteURL(baseURL, page)
+		},
+	}
+}
+
+// JekyllMaxLevels is the number of levels of the navigation of the Just
+// the Docs theme, which only supports a parent and a grand_parent.
+const JekyllMaxLevels = 3
+
+// jekyllLevel returns the level of page in the tree, 1 for the first page.
+func jekyllLevel(page *SitePage) int {
+	level := 1
+	for p := page.Parent; p != nil; p = p.Parent {
+		level++
+	}
+	return level
+}
+
+// jekyllNavParent returns the page page is nested under in the navigation of
+// Just the Docs, nil for the first page of the tree. The pages deeper than
+// JekyllMaxLevels are nested under their ancestor at the level above the last.
+func jekyllNavParent(page *SitePage) *SitePage {
+	parent := page.Parent
+	for parent != nil && jekyllLevel(parent) >= JekyllMaxLevels {
+		parent = parent.Parent
+	}
+	return parent
+}
+
+// JekyllPreset returns the preset of Jekyll, with the navigation of the
+// Just the Docs theme. The pages are served at baseURL followed by their
+// Ref (e.g., "/commands/root_sub/"), relative to the baseurl of the site.
+// The navigation has at most JekyllMaxLevels levels: the pages of deeper
+// commands are listed at the last level, next to their ancestor at that
+// level. The markdown of the pages is in a raw block, so that Liquid does
+// not evaluate the "{{" and "{%" of the commands, except for the links.
+func JekyllPreset(baseURL string) SitePreset {
+	return SitePreset{
+		FrontMatter: func(page *SitePage) (string, error) {
+			fields := yaml.MapSlice{
+				{Key: "title", Value: page.Command.CommandPath()},
+				{Key: "permalink", Value: siteURL(baseURL, page)},
+				{Key: "nav_order", Value: page.Weight},
+			}
+			if parent := jekyllNavParent(page); parent != nil {
+				fields = append(fields, yaml.MapItem{Key: "parent", Value: parent.Command.CommandPath()})
+				if grandParent := jekyllNavParent(parent); grandParent != nil {
+					fields = append(fields, yaml.MapItem{Key: "grand_parent", Value: grandParent.Command.CommandPath()})
+				}
+			}
+			if len(page.Children) > 0 && jekyllLevel(page) < JekyllMaxLevels {
+				fields = append(fields, yaml.MapItem{Key: "has_children", Value: true})
+			}
+			return frontMatter(fields)
+		},
+		Link: func(page *SitePage) string {
+			return "{% endraw %}{{ '" + siteURL(baseURL, page) + "' | relative_url }}{% raw %}"
+		},
+		Body: func(page *SitePage, markdown string) string {
+			return "{% raw %}\n" + markdown + "{% endraw %}\n"
+		},
+	}
+}
+
+// DocusaurusSidebarFile is the name of the sidebar DocusaurusPreset writes
+// next to the pages.
+const DocusaurusSidebarFile = "sidebar.json"
+
+// docusaurusItem is an item of a Docusaurus sidebar: a doc, or a category
+// linking to a doc for the pages having children.
+type docusaurusItem struct {
+	Type  string            `json:"type"`
+	ID    string            `json:"id,omitempty"`
+	Label string            `json:"label"`
+	Link  *docusaurusItem   `json:"link,omitempty"`
+	Items []*docusaurusItem `json:"items,omitempty"`
+}
+
+// DocusaurusPreset returns the preset of Docusaurus. The pages link to each
+// other with relative links to their files, which Docusaurus resolves. As
+// the pages are in the same directory, they are nested like the commands in
+// the sidebar written to DocusaurusSidebarFile, whose doc ids are prefixed
+// with dir, the directory of the pages relative to the docs directory
+// (e.g., "commands"). It can be used in sidebars.js:
+//
+//	module.exports = {
+//	  commands: require('./docs/commands/sidebar.json'),
+//	};
+func DocusaurusPreset(dir string) SitePreset {
+	docID := func(page *SitePage) string {
+		return path.Join(dir, page.Ref)
+	}
+	var sidebarItem func(page *SitePage) *docusaurusItem
+	sidebarItem = func(page *SitePage) *docusaurusItem {
+		if len(page.Children) == 0 {
+			return &docusaurusItem{Type: "doc", ID: docID(page), Label: page.Command.Name()}
+		}
+		item := &docusaurusItem{
+			Type:  "category",
+			Label: page.Command.Name(),
+			Link:  &docusaurusItem{Type: "doc", ID: docID(page)},
+		}
+		for _, child := range page.Children {
+			item.Items = append(item.Items, sidebarItem(child))
+		}
+		return item
+	}
+
+	return SitePreset{
+		FrontMatter: func(page *SitePage) (string, error) {
+			return frontMatter(yaml.MapSlice{
+				{Key: "id", Value: page.Ref},
+				{Key: "title", Value: page.Command.CommandPath()},
+				{Key: "sidebar_label", Value: page.Command.Name()},
+				{Key: "sidebar_position", Value: page.Weight},
+			})
+		},
+		Link: func(page *SitePage) string {
+			return page.Ref + ".md"
+		},
+		Index: func(root *SitePage) (string, []byte, error) {
+			sidebar, err := json.MarshalIndent([]*docusaurusItem{sidebarItem(root)}, "", "  ")
+			if err != nil {
+				return "", nil, err
+			}
+			return DocusaurusSidebarFile, append(sidebar, '\n'), nil
+		},
+	}
+}
+
+// GenMarkdownSiteTree is the same as GenMarkdownTreeFromOpts, but writes
+// the front matter of preset at the start of the pages, links them with the
+// links of preset and writes its index, if any.
+func GenMarkdownSiteTree(cmd *cobra.Command, preset SitePreset, opts TreeOptions) error {
+	opts = opts.withDefaults(defaultNaming, DefaultTreeFilter)
+	files, err := treeFiles(cmd, opts, ".md")
+	if err != nil {
+		return err
+	}
+
+	pages := make(map[*cobra.Command]*SitePage)
+	refs := make(map[string]*SitePage)
+	for i, file := range files {
+		page := &SitePage{Command: file.cmd, Ref: opts.Naming(file.cmd), Weight: i + 1}
+		if parent, ok := pages[file.cmd.Parent()]; ok {
+			page.Parent = parent
+			parent.Children = append(parent.Children, page)
+		}
+		pages[file.cmd] = page
+		refs[page.Ref] = page
+	}
+	linkHandler := func(name, ref string) string {
+		if page, ok := refs[ref]; ok {
+			return preset.Link(page)
+		}
+		return ref + ".md"
+	}
+
+	err = genTree(cmd, opts, defaultNaming, DefaultTreeFilter, ".md", func(c *cobra.Command, filename string, w io.Writer) error {
+		front, err := preset.FrontMatter(pages[c])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, front); err != nil {
+			return err
+		}
+		data := newDocData(c, opts.Naming, opts.Filter, linkHandler)
+		if preset.Body == nil {
+			return defaultMarkdownTemplate.Execute(w, data)
+		}
+		buf := new(bytes.Buffer)
+		if err := defaultMarkdownTemplate.Execute(buf, data); err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, preset.Body(pages[c], buf.String()))
+		return err
+	})
+	if err != nil || preset.Index == nil {
+		return err
+	}
+
+	name, content, err := preset.Index(pages[cmd])
+	if err != nil {
+		return err
+	}
+	return writeTreeContent(opts.FS, name, content)
+}
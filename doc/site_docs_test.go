@@ -0,0 +1,132 @@
+package doc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGenMarkdownSiteTreeHugo(t *testing.T) {
+	files := cobra.NewMemFS()
+	if err := GenMarkdownSiteTree(rootCmd, HugoPreset("/commands/", "docs"), TreeOptions{FS: files}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "root.md root_echo.md root_echo_echosub.md root_echo_times.md"
+	if got := fileNames(files); got != expected {
+		t.Errorf("Expected files %q, got %q", expected, got)
+	}
+	echo := readFile(t, files, "root_echo.md")
+	checkStringContains(t, echo, `---
+title: root echo
+linkTitle: echo
+slug: root_echo
+url: /commands/root_echo/
+weight: 2
+menu:
+  docs:
+    identifier: root_echo
+    name: echo
+    parent: root
+    weight: 2
+---
+
+## root echo
+`)
+	checkStringContains(t, echo, "* [root](/commands/root/)")
+	checkStringContains(t, echo, "* [root echo times](/commands/root_echo_times/)")
+	checkStringOmits(t, readFile(t, files, "root.md"), "parent:")
+}
+
+func TestGenMarkdownSiteTreeJekyll(t *testing.T) {
+	files := cobra.NewMemFS()
+	if err := GenMarkdownSiteTree(rootCmd, JekyllPreset("/commands"), TreeOptions{FS: files}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkStringContains(t, readFile(t, files, "root.md"), "---\ntitle: root\npermalink: /commands/root/\nnav_order: 1\nhas_children: true\n---\n")
+	times := readFile(t, files, "root_echo_times.md")
+	checkStringContains(t, times, "---\ntitle: root echo times\npermalink: /commands/root_echo_times/\nnav_order: 4\nparent: root echo\ngrand_parent: root\n---\n")
+	checkStringContains(t, times, "---\n\n{% raw %}\n## root echo times\n")
+	checkStringContains(t, times, "* [root echo]({% endraw %}{{ '/commands/root_echo/' | relative_url }}{% raw %})")
+	if !strings.HasSuffix(times, "{% endraw %}\n") {
+		t.Errorf("Expected the page to end with the raw block, got %q", times)
+	}
+}
+
+func TestGenMarkdownSiteTreeJekyllLevels(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	parent := root
+	for _, name := range []string{"a", "b", "c", "d"} {
+		child := &cobra.Command{Use: name, Short: "Uses {{ .Values }}", Run: emptyRun}
+		parent.AddCommand(child)
+		parent = child
+	}
+	files := cobra.NewMemFS()
+	if err := GenMarkdownSiteTree(root, JekyllPreset("/commands"), TreeOptions{FS: files}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkStringContains(t, readFile(t, files, "root_a.md"), "nav_order: 2\nparent: root\nhas_children: true\n---\n")
+	checkStringContains(t, readFile(t, files, "root_a_b.md"), "nav_order: 3\nparent: root a\ngrand_parent: root\n---\n")
+	// The deeper pages are at the last level as well
+	for _, name := range []string{"root_a_b_c.md", "root_a_b_c_d.md"} {
+		page := readFile(t, files, name)
+		checkStringContains(t, page, "parent: root a\ngrand_parent: root\n---\n")
+		checkStringOmits(t, page, "has_children")
+		checkStringContains(t, page, "{% raw %}\n## root a b c")
+		checkStringContains(t, page, "Uses {{ .Values }}")
+	}
+}
+
+func TestGenMarkdownSiteTreeDocusaurus(t *testing.T) {
+	files := cobra.NewMemFS()
+	if err := GenMarkdownSiteTree(rootCmd, DocusaurusPreset("commands"), TreeOptions{FS: files}); err != nil {
+		t.Fatal(err)
+	}
+
+	echo := readFile(t, files, "root_echo.md")
+	checkStringContains(t, echo, "---\nid: root_echo\ntitle: root echo\nsidebar_label: echo\nsidebar_position: 2\n---\n")
+	checkStringContains(t, echo, "* [root echo times](root_echo_times.md)")
+
+	var sidebar []*docusaurusItem
+	if err := json.Unmarshal([]byte(readFile(t, files, DocusaurusSidebarFile)), &sidebar); err != nil {
+		t.Fatal(err)
+	}
+	var describe func(items []*docusaurusItem) string
+	describe = func(items []*docusaurusItem) string {
+		if len(items) == 0 {
+			return ""
+		}
+		var s []string
+		for _, item := range items {
+			id := item.ID
+			if item.Link != nil {
+				id = item.Link.ID
+			}
+			s = append(s, item.Label+"="+id+describe(item.Items))
+		}
+		return "(" + strings.Join(s, " ") + ")"
+	}
+	expected := "(root=commands/root(echo=commands/root_echo(echosub=commands/root_echo_echosub times=commands/root_echo_times)))"
+	if got := describe(sidebar); got != expected {
+		t.Errorf("Expected sidebar %s, got %s", expected, got)
+	}
+}
+
+func TestGenMarkdownSiteTreeFrontMatterError(t *testing.T) {
+	preset := SitePreset{
+		FrontMatter: func(page *SitePage) (string, error) {
+			return "", errors.New("no front matter for " + page.Ref)
+		},
+		Link: func(page *SitePage) string { return page.Ref },
+	}
+	err := GenMarkdownSiteTree(rootCmd, preset, TreeOptions{FS: cobra.NewMemFS()})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), "no front matter for root")
+}
@@ -0,0 +1,216 @@
+package doc
+
+import (
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DocData is the data the templates of GenMarkdownTemplate and
+// GenReSTTemplate are executed with.
+type DocData struct {
+	// Command is the command documented.
+	Command *cobra.Command
+	// Name is the path of the command (e.g., "root sub").
+	Name string
+	// Ref is the reference of the command, which is also the basename of its
+	// page in a tree (e.g., "root_sub").
+	Ref   string
+	Short string
+	// Long is the long description of the command, or its short description
+	// if it has none.
+	Long     string
+	UseLine  string
+	Runnable bool
+	Example  string
+	Flags    DocFlags
+	// Parents are the ancestors of the command, starting with the root command.
+	Parents []DocLink
+	// Parent is the parent of the command, nil for the root command.
+	Parent *DocLink
+	// Subcommands are the available subcommands of the command, sorted by name.
+	Subcommands []DocLink
+	// AutoGenTag is the "Auto generated by spf13/cobra" tag, empty if
+	// DisableAutoGenTag is set on the command or one of its parents.
+	AutoGenTag string
+}
+
+// DocFlags are the flags of a command, by category. The hidden flags are omitted.
+type DocFlags struct {
+	// Local are the flags of the command, Inherited the flags it inherits
+	// from its parents.
+	Local     []DocFlag
+	Inherited []DocFlag
+	// LocalUsages and InheritedUsages are the usages of the flags, as printed
+	// in the help of the command.
+	LocalUsages     string
+	InheritedUsages string
+}
+
+// DocFlag is a flag of a command.
+type DocFlag struct {
+	Name      string
+	Shorthand string
+	// Type is the type of the value of the flag (e.g., "string").
+	Type       string
+	Default    string
+	Usage      string
+	Required   bool
+	Deprecated string
+}
+
+// DocLink is a command another command refers to.
+type DocLink struct {
+	Name  string
+	Short string
+	// Link is the link to the command, as returned by the linkHandler.
+	Link string
+}
+
+// TemplateFuncs returns the functions available to the templates of
+// GenMarkdownTemplate and GenReSTTemplate, which must be added to a template
+// before it is parsed:
+//
+//	indent PREFIX S  prefixes each line of S with PREFIX.
+//	repeat S N       repeats S N times, e.g. to underline a ReST title.
+//	lower S, upper S, replace S OLD NEW, trimSpace S
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"indent":    func(prefix, s string) string { return indentString(s, prefix) },
+		"repeat":    func(s string, n int) string { return strings.Repeat(s, n) },
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"replace":   func(s, old, new string) string { return strings.Replace(s, old, new, -1) },
+		"trimSpace": strings.TrimSpace,
+	}
+}
+
+// DefaultMarkdownTemplate is the template GenMarkdown executes.
+const DefaultMarkdownTemplate = "## {{.Name}}\n\n" +
+	"{{.Short}}\n\n" +
+	"### Synopsis\n\n" +
+	"{{.Long}}\n\n" +
+	"{{if .Runnable}}```\n{{.UseLine}}\n```\n\n{{end}}" +
+	"{{if .Example}}### Examples\n\n```\n{{.Example}}\n```\n\n{{end}}" +
+	"{{if .Flags.Local}}### Options\n\n```\n{{.Flags.LocalUsages}}```\n\n{{end}}" +
+	"{{if .Flags.Inherited}}### Options inherited from parent commands\n\n```\n{{.Flags.InheritedUsages}}```\n\n{{end}}" +
+	"{{if or .Parent .Subcommands}}### SEE ALSO\n\n" +
+	"{{with .Parent}}* [{{.Name}}]({{.Link}})\t - {{.Short}}\n{{end}}" +
+	"{{range .Subcommands}}* [{{.Name}}]({{.Link}})\t - {{.Short}}\n{{end}}" +
+	"\n{{end}}" +
+	"{{with .AutoGenTag}}###### {{.}}\n{{end}}"
+
+// DefaultReSTTemplate is the template GenReST executes.
+const DefaultReSTTemplate = ".. _{{.Ref}}:\n\n" +
+	"{{.Name}}\n{{repeat \"-\" (len .Name)}}\n\n" +
+	"{{.Short}}\n\n" +
+	"Synopsis\n~~~~~~~~\n\n\n" +
+	"{{.Long}}\n\n" +
+	"{{if .Runnable}}::\n\n  {{.UseLine}}\n\n{{end}}" +
+	"{{if .Example}}Examples\n~~~~~~~~\n\n::\n\n{{indent \"  \" .Example}}\n\n{{end}}" +
+	"{{if .Flags.Local}}Options\n~~~~~~~\n\n::\n\n{{.Flags.LocalUsages}}\n{{end}}" +
+	"{{if .Flags.Inherited}}Options inherited from parent commands\n" +
+	"~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~\n\n::\n\n{{.Flags.InheritedUsages}}\n{{end}}" +
+	"{{if or .Parent .Subcommands}}SEE ALSO\n~~~~~~~~\n\n" +
+	"{{with .Parent}}* {{.Link}} \t - {{.Short}}\n{{end}}" +
+	"{{range .Subcommands}}* {{.Link}} \t - {{.Short}}\n{{end}}" +
+	"\n{{end}}" +
+	"{{with .AutoGenTag}}*{{.}}*\n{{end}}"
+
+var (
+	defaultMarkdownTemplate = template.Must(template.New("markdown").Funcs(TemplateFuncs()).Parse(DefaultMarkdownTemplate))
+	defaultReSTTemplate     = template.Must(template.New("rest").Funcs(TemplateFuncs()).Parse(DefaultReSTTemplate))
+)
+
+// newDocData returns the data documenting cmd, whose reference and the
+// references of the commands it refers to are named by naming. The linkHandler
+// receives the path and the reference of the commands cmd refers to.
+func newDocData(cmd *cobra.Command, naming NamingStrategy, filter func(*cobra.Command) bool, linkHandler func(string, string) string) *DocData {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	data := &DocData{
+		Command:  cmd,
+		Name:     cmd.CommandPath(),
+		Ref:      naming(cmd),
+		Short:    cmd.Short,
+		Long:     cmd.Long,
+		UseLine:  cmd.UseLine(),
+		Runnable: cmd.Runnable(),
+		Example:  cmd.Example,
+		Flags:    newDocFlags(cmd),
+	}
+	if len(data.Long) == 0 {
+		data.Long = data.Short
+	}
+
+	link := func(c *cobra.Command) DocLink {
+		name := c.CommandPath()
+		return DocLink{Name: name, Short: c.Short, Link: linkHandler(name, naming(c))}
+	}
+	if cmd.HasParent() {
+		parent := link(cmd.Parent())
+		data.Parent = &parent
+		cmd.VisitParents(func(c *cobra.Command) {
+			data.Parents = append([]DocLink{link(c)}, data.Parents...)
+			if c.DisableAutoGenTag {
+				cmd.DisableAutoGenTag = c.DisableAutoGenTag
+			}
+		})
+	}
+	children := cmd.Commands()
+	sort.Sort(byName(children))
+	for _, child := range children {
+		if !filter(child) {
+			continue
+		}
+		data.Subcommands = append(data.Subcommands, link(child))
+	}
+
+	if !cmd.DisableAutoGenTag {
+		data.AutoGenTag = "Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006")
+	}
+	return data
+}
+
+func newDocFlags(cmd *cobra.Command) DocFlags {
+	flags := DocFlags{}
+	local, inherited := cmd.NonInheritedFlags(), cmd.InheritedFlags()
+	if local.HasAvailableFlags() {
+		flags.Local = newDocFlagList(local)
+		flags.LocalUsages = cobra.EnumFlagUsages(local)
+	}
+	if inherited.HasAvailableFlags() {
+		flags.Inherited = newDocFlagList(inherited)
+		flags.InheritedUsages = cobra.EnumFlagUsages(inherited)
+	}
+	return flags
+}
+
+func newDocFlagList(flags *pflag.FlagSet) []DocFlag {
+	var result []DocFlag
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		docFlag := DocFlag{
+			Name:       flag.Name,
+			Type:       flag.Value.Type(),
+			Default:    flag.DefValue,
+			Usage:      cobra.EnumFlagUsage(flag),
+			Deprecated: flag.Deprecated,
+		}
+		if len(flag.ShorthandDeprecated) == 0 {
+			docFlag.Shorthand = flag.Shorthand
+		}
+		if required := flag.Annotations[cobra.BashCompOneRequiredFlag]; len(required) > 0 && required[0] == "true" {
+			docFlag.Required = true
+		}
+		result = append(result, docFlag)
+	})
+	return result
+}
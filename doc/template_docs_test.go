@@ -0,0 +1,74 @@
+package doc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+func TestGenMarkdownTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("page").Funcs(TemplateFuncs()).Parse(`---
+title: {{.Name}}
+parents: [{{range $i, $p := .Parents}}{{if $i}}, {{end}}{{$p.Name}}{{end}}]
+---
+{{template "body" .}}
+{{define "body"}}{{range .Flags.Local}}{{.Name}}:{{.Type}}:{{.Default}}
+{{end}}{{range .Flags.Inherited}}inherited {{.Name}}
+{{end}}{{range .Subcommands}}[{{.Name}}]({{.Link}})
+{{end}}{{upper .Short}}{{end}}`))
+
+	buf := new(bytes.Buffer)
+	if err := GenMarkdownTemplate(echoSubCmd, buf, tmpl, func(s string) string { return "/" + s }); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "---\ntitle: root echo echosub\nparents: [root, root echo]\n---\n")
+	checkStringContains(t, output, "help:bool:false\n")
+	checkStringContains(t, output, "inherited strone\n")
+	checkStringContains(t, output, "SECOND SUB COMMAND FOR ECHO")
+
+	buf.Reset()
+	if err := GenMarkdownTemplate(rootCmd, buf, tmpl, func(s string) string { return "/" + s }); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "[root echo](/root_echo.md)\n")
+}
+
+func TestGenReSTTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("page").Funcs(TemplateFuncs()).Parse(
+		"{{.Name}}\n{{repeat \"=\" (len .Name)}}\n\n.. note:: {{.Short}}\n\n{{indent \"   \" .Example}}\n{{with .Parent}}{{.Link}}{{end}}\n"))
+
+	buf := new(bytes.Buffer)
+	if err := GenReSTTemplate(echoCmd, buf, tmpl, defaultLinkHandler); err != nil {
+		t.Fatal(err)
+	}
+	expected := "root echo\n=========\n\n.. note:: " + echoCmd.Short + "\n\n   " + echoCmd.Example + "\n`root <root.rst>`_\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestGenMarkdownTreeTemplate(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-gen-md-tree")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	tmpl := template.Must(template.New("page").Parse("# {{.Ref}}\n"))
+	if err := GenMarkdownTreeTemplate(rootCmd, tmpdir, tmpl, func(s string) string { return s }); err != nil {
+		t.Fatalf("GenMarkdownTreeTemplate failed: %v", err)
+	}
+
+	page, err := ioutil.ReadFile(filepath.Join(tmpdir, "root_echo_times.md"))
+	if err != nil {
+		t.Fatalf("Expected file 'root_echo_times.md' to exist")
+	}
+	if string(page) != "# root_echo_times\n" {
+		t.Errorf("Unexpected page: %q", page)
+	}
+}
@@ -0,0 +1,177 @@
+package doc
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// NamingStrategy returns the name of the file of a command in a tree,
+// without its extension.
+type NamingStrategy func(cmd *cobra.Command) string
+
+// JoinNaming names the file of a command after its path, with the names of
+// the commands joined by separator (e.g., "root_sub" with "_"). The names are
+// not unique if the names of the commands contain the separator: "root sub-a"
+// and "root sub a" are both named "root-sub-a" with "-".
+func JoinNaming(separator string) NamingStrategy {
+	return func(cmd *cobra.Command) string {
+		return strings.Replace(cmd.CommandPath(), " ", separator, -1)
+	}
+}
+
+// EscapedNaming is like JoinNaming, but escapes the characters of the
+// separator, and "~", within the names of the commands as "~" followed by
+// their hexadecimal code, so that the names are unique: "root sub-a" is named
+// "root-sub~2Da" and "root sub a" is named "root-sub-a" with "-". The
+// separator must not contain "~".
+func EscapedNaming(separator string) NamingStrategy {
+	escaped := separator + "~"
+	return func(cmd *cobra.Command) string {
+		var names []string
+		for c := cmd; c != nil; c = c.Parent() {
+			var name strings.Builder
+			for _, b := range []byte(c.Name()) {
+				if strings.IndexByte(escaped, b) >= 0 {
+					fmt.Fprintf(&name, "~%02X", b)
+				} else {
+					name.WriteByte(b)
+				}
+			}
+			names = append([]string{name.String()}, names...)
+		}
+		return strings.Join(names, separator)
+	}
+}
+
+// defaultNaming is the naming of the pages of all the trees but man pages.
+var defaultNaming = JoinNaming("_")
+
+// namingOr returns naming, or def if naming is nil.
+func namingOr(naming, def NamingStrategy) NamingStrategy {
+	if naming == nil {
+		return def
+	}
+	return naming
+}
+
+// DefaultTreeFilter selects the commands having a file in a tree by default:
+// the available commands, which are neither hidden nor deprecated, that are
+// not additional help topics.
+func DefaultTreeFilter(cmd *cobra.Command) bool {
+	return cmd.IsAvailableCommand() && !cmd.IsAdditionalHelpTopicCommand()
+}
+
+// TreeOptions are the options of the tree generators, such as
+// GenMarkdownTreeFromOpts.
+type TreeOptions struct {
+	// Naming names the files of the commands, and the links between them.
+	// If nil, the names of the commands are joined with "_" (or with "-"
+	// for man pages).
+	Naming NamingStrategy
+	// Filter selects the descendants of the command having a file. The
+	// descendants of a command which is not selected do not have one either.
+	// If nil, DefaultTreeFilter is used (and the help topics are selected as
+	// well for man pages).
+	Filter func(*cobra.Command) bool
+	// FS is the filesystem the files are written to, such as a cobra.MemFS
+	// or a cobra.ZipFS. If nil, the files are created in Dir.
+	FS cobra.WriteFS
+	// Dir is the directory the files are created in if FS is nil.
+	Dir string
+	// CaseInsensitive returns an error if the names of two files only
+	// differ by case, as they would overwrite each other on case-insensitive
+	// filesystems, such as the default ones of macOS and Windows.
+	CaseInsensitive bool
+}
+
+// treeFile is the file of a command of a tree.
+type treeFile struct {
+	cmd  *cobra.Command
+	name string
+}
+
+// treeFiles returns the files of cmd and its descendants selected by
+// opts.Filter, named by opts.Naming with the extension ext. It returns an
+// error if two commands have the same file name, regardless of case if
+// opts.CaseInsensitive is set.
+func treeFiles(cmd *cobra.Command, opts TreeOptions, ext string) ([]treeFile, error) {
+	var files []treeFile
+	named := make(map[string]*cobra.Command)
+	var visit func(c *cobra.Command) error
+	visit = func(c *cobra.Command) error {
+		name := opts.Naming(c) + ext
+		key := name
+		if opts.CaseInsensitive {
+			key = strings.ToLower(name)
+		}
+		if other, ok := named[key]; ok {
+			return fmt.Errorf("commands %q and %q have the same file name %q", other.CommandPath(), c.CommandPath(), name)
+		}
+		named[key] = c
+		files = append(files, treeFile{cmd: c, name: name})
+
+		for _, child := range c.Commands() {
+			if !opts.Filter(child) {
+				continue
+			}
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(cmd); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// withDefaults returns opts, with the defaultNaming and defaultFilter of the
+// format if opts has none, and the FS creating the files in Dir if it has none.
+func (opts TreeOptions) withDefaults(defaultNaming NamingStrategy, defaultFilter func(*cobra.Command) bool) TreeOptions {
+	opts.Naming = namingOr(opts.Naming, defaultNaming)
+	if opts.Filter == nil {
+		opts.Filter = defaultFilter
+	}
+	if opts.FS == nil {
+		opts.FS = cobra.DirFS(opts.Dir)
+	}
+	return opts
+}
+
+// genTree writes the files of cmd and its descendants selected by opts with
+// gen, which receives the path of the file. The defaultNaming and
+// defaultFilter of the format are used if opts has none. No file is written
+// if two commands have the same file name.
+func genTree(cmd *cobra.Command, opts TreeOptions, defaultNaming NamingStrategy, defaultFilter func(*cobra.Command) bool, ext string,
+	gen func(c *cobra.Command, filename string, w io.Writer) error) error {
+	opts = opts.withDefaults(defaultNaming, defaultFilter)
+	files, err := treeFiles(cmd, opts, ext)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := genTreeFile(file, filepath.Join(opts.Dir, file.name), opts.FS, gen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func genTreeFile(file treeFile, filename string, fs cobra.WriteFS, gen func(*cobra.Command, string, io.Writer) error) error {
+	return cobra.WriteFile(fs, file.name, func(w io.Writer) error {
+		return gen(file.cmd, filename, w)
+	})
+}
+
+// writeTreeContent writes the file name of fs with content.
+func writeTreeContent(fs cobra.WriteFS, name string, content []byte) error {
+	return cobra.WriteFile(fs, name, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
@@ -0,0 +1,210 @@
+package doc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func fileNames(fs *cobra.MemFS) string {
+	return strings.Join(fs.Names(), " ")
+}
+
+func readFile(t *testing.T, fs *cobra.MemFS, name string) string {
+	content, err := fs.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// collidingCmd returns the commands "root sub-a" and "root sub a".
+func collidingCmd() *cobra.Command {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	sub := &cobra.Command{Use: "sub", Run: emptyRun}
+	sub.AddCommand(&cobra.Command{Use: "a", Run: emptyRun})
+	root.AddCommand(sub, &cobra.Command{Use: "sub-a", Run: emptyRun})
+	return root
+}
+
+func TestGenTreeCollision(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-gen-tree-collision")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	err = GenManTree(collidingCmd(), nil, tmpdir)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), `"root-sub-a.1"`)
+
+	infos, err := ioutil.ReadDir(tmpdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("Expected no file to be written, got %d", len(infos))
+	}
+}
+
+func TestGenTreeCaseCollision(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "Sub", Run: emptyRun}, &cobra.Command{Use: "sub", Run: emptyRun})
+
+	files := cobra.NewMemFS()
+	if err := GenYamlTreeFromOpts(root, TreeOptions{FS: files}); err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := fileNames(files), "root.yaml root_Sub.yaml root_sub.yaml"; got != expected {
+		t.Errorf("Expected files %q, got %q", expected, got)
+	}
+
+	err := GenYamlTreeFromOpts(root, TreeOptions{FS: cobra.NewMemFS(), CaseInsensitive: true})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	checkStringContains(t, err.Error(), `commands "root Sub" and "root sub"`)
+}
+
+func TestGenTreeEscapedNaming(t *testing.T) {
+	files := cobra.NewMemFS()
+	opts := GenManTreeOptions{Naming: EscapedNaming("-"), FS: files}
+	if err := GenManTreeFromOpts(collidingCmd(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "root-sub-a.1 root-sub.1 root-sub~2Da.1 root.1"
+	if got := fileNames(files); got != expected {
+		t.Errorf("Expected files %q, got %q", expected, got)
+	}
+	checkStringContains(t, readFile(t, files, "root-sub-a.1"), `root\-sub\-a`)
+
+	// The separator at the boundary of the names is escaped as well
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	sub := &cobra.Command{Use: "sub", Run: emptyRun}
+	sub.AddCommand(&cobra.Command{Use: "_a", Run: emptyRun})
+	subSep := &cobra.Command{Use: "sub_", Run: emptyRun}
+	subSep.AddCommand(&cobra.Command{Use: "a", Run: emptyRun})
+	root.AddCommand(sub, subSep)
+	files = cobra.NewMemFS()
+	if err := GenYamlTreeFromOpts(root, TreeOptions{Naming: EscapedNaming("_"), FS: files}); err != nil {
+		t.Fatal(err)
+	}
+	expected = "root.yaml root_sub.yaml root_sub_~5Fa.yaml root_sub~5F.yaml root_sub~5F_a.yaml"
+	if got := fileNames(files); got != expected {
+		t.Errorf("Expected files %q, got %q", expected, got)
+	}
+}
+
+func TestGenMarkdownTreeNaming(t *testing.T) {
+	files := cobra.NewMemFS()
+	opts := TreeOptions{Naming: EscapedNaming("_"), FS: files, Dir: "docs"}
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "sub_a", Short: "sub a", Run: emptyRun})
+	if err := GenMarkdownTreeFromOpts(root, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "root.md root_sub~5Fa.md"
+	if got := fileNames(files); got != expected {
+		t.Errorf("Expected files %q, got %q", expected, got)
+	}
+	checkStringContains(t, readFile(t, files, "root.md"), "[root sub_a](root_sub~5Fa.md)")
+}
+
+func TestGenTreeFilter(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	hidden := &cobra.Command{Use: "hidden", Hidden: true, Run: emptyRun}
+	hidden.AddCommand(&cobra.Command{Use: "child", Run: emptyRun})
+	root.AddCommand(hidden, &cobra.Command{Use: "old", Deprecated: "use new", Run: emptyRun})
+
+	files := cobra.NewMemFS()
+	if err := GenJSONTreeFromOpts(root, TreeOptions{FS: files}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fileNames(files); got != "root.json" {
+		t.Errorf("Expected files %q, got %q", "root.json", got)
+	}
+
+	files = cobra.NewMemFS()
+	opts := TreeOptions{
+		Filter: func(c *cobra.Command) bool { return c.Name() != "help" },
+		FS:     files,
+	}
+	if err := GenJSONTreeFromOpts(root, opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := "root.json root_hidden.json root_hidden_child.json root_old.json"
+	if got := fileNames(files); got != expected {
+		t.Errorf("Expected files %q, got %q", expected, got)
+	}
+}
+
+func TestGenTreeFilterSeeAlso(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	root.AddCommand(&cobra.Command{Use: "hidden", Short: "hidden command", Hidden: true, Run: emptyRun})
+	opts := TreeOptions{Filter: func(c *cobra.Command) bool { return c.Name() != "help" }}
+
+	opts.FS = cobra.NewMemFS()
+	if err := GenMarkdownTreeFromOpts(root, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, readFile(t, opts.FS.(*cobra.MemFS), "root.md"), "[root hidden](root_hidden.md)")
+
+	opts.FS = cobra.NewMemFS()
+	if err := GenReSTTreeFromOpts(root, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, readFile(t, opts.FS.(*cobra.MemFS), "root.rst"), "`root hidden <root_hidden.rst>`_")
+
+	opts.FS = cobra.NewMemFS()
+	if err := GenAsciidocTreeFromOpts(root, opts); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, readFile(t, opts.FS.(*cobra.MemFS), "root.adoc"), "xref:root_hidden.adoc[root hidden]")
+
+	opts.FS = cobra.NewMemFS()
+	if err := GenHTMLTreeFromOpts(root, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, readFile(t, opts.FS.(*cobra.MemFS), "root.html"), `<a href="root_hidden.html">root hidden</a>`)
+
+	// The commands which are not selected are not linked to
+	opts.FS = cobra.NewMemFS()
+	if err := GenMarkdownTreeFromOpts(root, TreeOptions{FS: opts.FS}); err != nil {
+		t.Fatal(err)
+	}
+	checkStringOmits(t, readFile(t, opts.FS.(*cobra.MemFS), "root.md"), "root_hidden.md")
+}
+
+func TestGenTreeDir(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "test-gen-tree-dir")
+	if err != nil {
+		t.Fatalf("Failed to create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	var prepended []string
+	filePrepender := func(filename string) string {
+		prepended = append(prepended, filename)
+		return ""
+	}
+	if err := GenReSTTreeCustom(rootCmd, tmpdir, filePrepender, defaultLinkHandler); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range prepended {
+		if _, err := os.Stat(filename); err != nil {
+			t.Errorf("Expected file %q to exist", filename)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpdir, "root_echo_times.rst")); err != nil {
+		t.Errorf("Expected file 'root_echo_times.rst' to exist")
+	}
+}
@@ -17,20 +17,20 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Test to see if we have a reason to print See Also information in docs
 // Basically this is a test for a parent commend or a subcommand which is
-// both not deprecated and not the autogenerated help command.
-func hasSeeAlso(cmd *cobra.Command) bool {
+// selected by filter, such as DefaultTreeFilter.
+func hasSeeAlso(cmd *cobra.Command, filter func(*cobra.Command) bool) bool {
 	if cmd.HasParent() {
 		return true
 	}
 	for _, c := range cmd.Commands() {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-			continue
+		if filter(c) {
+			return true
 		}
-		return true
 	}
 	return false
 }
@@ -44,6 +44,24 @@ func forceMultiLine(s string) string {
 	return s
 }
 
+// allowedValues returns the values accepted by a flag marked with
+// cobra.MarkFlagEnum, each followed by its description if it has one.
+func allowedValues(flag *pflag.Flag) []string {
+	var result []string
+	for _, v := range flag.Annotations[cobra.FlagEnumValues] {
+		result = append(result, strings.Replace(v, "\t", " - ", 1))
+	}
+	return result
+}
+
+// helpFlag returns a copy of flag whose usage is the one shown in the help,
+// listing the allowed values of an enum flag.
+func helpFlag(flag *pflag.Flag) *pflag.Flag {
+	f := *flag
+	f.Usage = cobra.EnumFlagUsage(flag)
+	return &f
+}
+
 type byName []*cobra.Command
 
 func (s byName) Len() int           { return len(s) }
@@ -14,12 +14,8 @@
 package doc
 
 import (
-	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 	"sort"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -27,10 +23,11 @@ import (
 )
 
 type cmdOption struct {
-	Name         string
-	Shorthand    string `yaml:",omitempty"`
-	DefaultValue string `yaml:"default_value,omitempty"`
-	Usage        string `yaml:",omitempty"`
+	Name          string
+	Shorthand     string   `yaml:",omitempty"`
+	DefaultValue  string   `yaml:"default_value,omitempty"`
+	Usage         string   `yaml:",omitempty"`
+	AllowedValues []string `yaml:"allowed_values,omitempty"`
 }
 
 type cmdDoc struct {
@@ -44,10 +41,9 @@ type cmdDoc struct {
 }
 
 // GenYamlTree creates yaml structured ref files for this command and all descendants
-// in the directory given. This function may not work
-// correctly if your command names have `-` in them. If you have `cmd` with two
-// subcmds, `sub` and `sub-third`, and `sub` has a subcommand called `third`
-// it is undefined which help output will be in the file `cmd-sub-third.1`.
+// in the directory given. It returns an error if two commands have the same
+// file name, such as `cmd sub-third` and `cmd sub third`, see
+// GenYamlTreeFromOpts to name the files differently.
 func GenYamlTree(cmd *cobra.Command, dir string) error {
 	identity := func(s string) string { return s }
 	emptyStr := func(s string) string { return "" }
@@ -56,30 +52,25 @@ func GenYamlTree(cmd *cobra.Command, dir string) error {
 
 // GenYamlTreeCustom creates yaml structured ref files.
 func GenYamlTreeCustom(cmd *cobra.Command, dir string, filePrepender, linkHandler func(string) string) error {
-	for _, c := range cmd.Commands() {
-		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
-			continue
-		}
-		if err := GenYamlTreeCustom(c, dir, filePrepender, linkHandler); err != nil {
-			return err
-		}
-	}
+	return genYamlTree(cmd, TreeOptions{Dir: dir}, filePrepender, linkHandler)
+}
 
-	basename := strings.Replace(cmd.CommandPath(), " ", "_", -1) + ".yaml"
-	filename := filepath.Join(dir, basename)
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+// GenYamlTreeFromOpts is the same as GenYamlTree, but
+// with the commands, the names of their files and the output chosen by opts.
+func GenYamlTreeFromOpts(cmd *cobra.Command, opts TreeOptions) error {
+	identity := func(s string) string { return s }
+	emptyStr := func(s string) string { return "" }
+	return genYamlTree(cmd, opts, emptyStr, identity)
+}
 
-	if _, err := io.WriteString(f, filePrepender(filename)); err != nil {
-		return err
-	}
-	if err := GenYamlCustom(cmd, f, linkHandler); err != nil {
-		return err
-	}
-	return nil
+func genYamlTree(cmd *cobra.Command, opts TreeOptions, filePrepender, linkHandler func(string) string) error {
+	opts = opts.withDefaults(defaultNaming, DefaultTreeFilter)
+	return genTree(cmd, opts, defaultNaming, DefaultTreeFilter, ".yaml", func(c *cobra.Command, filename string, w io.Writer) error {
+		if _, err := io.WriteString(w, filePrepender(filename)); err != nil {
+			return err
+		}
+		return genYaml(c, w, opts.Filter, linkHandler)
+	})
 }
 
 // GenYaml creates yaml output.
@@ -89,6 +80,11 @@ func GenYaml(cmd *cobra.Command, w io.Writer) error {
 
 // GenYamlCustom creates custom yaml output.
 func GenYamlCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) string) error {
+	return genYaml(cmd, w, DefaultTreeFilter, linkHandler)
+}
+
+// genYaml creates yaml output, with the commands selected by filter in SEE ALSO.
+func genYaml(cmd *cobra.Command, w io.Writer, filter func(*cobra.Command) bool, linkHandler func(string) string) error {
 	cmd.InitDefaultHelpCmd()
 	cmd.InitDefaultHelpFlag()
 
@@ -111,7 +107,7 @@ func GenYamlCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) str
 		yamlDoc.InheritedOptions = genFlagResult(flags)
 	}
 
-	if hasSeeAlso(cmd) {
+	if hasSeeAlso(cmd, filter) {
 		result := []string{}
 		if cmd.HasParent() {
 			parent := cmd.Parent()
@@ -120,7 +116,7 @@ func GenYamlCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) str
 		children := cmd.Commands()
 		sort.Sort(byName(children))
 		for _, child := range children {
-			if !child.IsAvailableCommand() || child.IsAdditionalHelpTopicCommand() {
+			if !filter(child) {
 				continue
 			}
 			result = append(result, child.Name()+" - "+child.Short)
@@ -130,8 +126,7 @@ func GenYamlCustom(cmd *cobra.Command, w io.Writer, linkHandler func(string) str
 
 	final, err := yaml.Marshal(&yamlDoc)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return err
 	}
 
 	if _, err := w.Write(final); err != nil {
@@ -149,17 +144,19 @@ func genFlagResult(flags *pflag.FlagSet) []cmdOption {
 		// Using len(flag.ShorthandDeprecated) > 0 can't handle this, others are ok.
 		if !(len(flag.ShorthandDeprecated) > 0) && len(flag.Shorthand) > 0 {
 			opt := cmdOption{
-				flag.Name,
-				flag.Shorthand,
-				flag.DefValue,
-				forceMultiLine(flag.Usage),
+				Name:          flag.Name,
+				Shorthand:     flag.Shorthand,
+				DefaultValue:  flag.DefValue,
+				Usage:         forceMultiLine(flag.Usage),
+				AllowedValues: allowedValues(flag),
 			}
 			result = append(result, opt)
 		} else {
 			opt := cmdOption{
-				Name:         flag.Name,
-				DefaultValue: forceMultiLine(flag.DefValue),
-				Usage:        forceMultiLine(flag.Usage),
+				Name:          flag.Name,
+				DefaultValue:  forceMultiLine(flag.DefValue),
+				Usage:         forceMultiLine(flag.Usage),
+				AllowedValues: allowedValues(flag),
 			}
 			result = append(result, opt)
 		}
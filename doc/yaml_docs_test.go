@@ -72,3 +72,41 @@ func BenchmarkGenYamlToFile(b *testing.B) {
 		}
 	}
 }
+
+func TestGenYamlEnumFlag(t *testing.T) {
+	c := &cobra.Command{Use: "render", Run: emptyRun}
+	c.Flags().String("output", "json", "output format")
+	if err := c.MarkFlagEnum("output", "json\tJSON document", "yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := GenYaml(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "allowed_values:\n  - json - JSON document\n  - yaml\n")
+	checkStringContains(t, output, "usage: output format\n")
+}
+
+func TestGenYamlTreeFilter(t *testing.T) {
+	root := &cobra.Command{Use: "root", Run: emptyRun}
+	root.AddCommand(
+		&cobra.Command{Use: "hidden", Short: "A hidden command", Hidden: true, Run: emptyRun},
+		&cobra.Command{Use: "skipped", Short: "A skipped command", Run: emptyRun},
+	)
+
+	// The SEE ALSO section lists the commands selected by the filter
+	files := cobra.NewMemFS()
+	opts := TreeOptions{
+		Filter: func(c *cobra.Command) bool { return c.Name() != "skipped" && c.Name() != "help" },
+		FS:     files,
+	}
+	if err := GenYamlTreeFromOpts(root, opts); err != nil {
+		t.Fatal(err)
+	}
+	output := readFile(t, files, "root.yaml")
+	checkStringContains(t, output, "hidden - A hidden command")
+	checkStringOmits(t, output, "skipped")
+}
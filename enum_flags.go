@@ -0,0 +1,245 @@
+package cobra
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// FlagEnumValues is the annotation listing the values accepted by a flag
+// marked with MarkFlagEnum. Each value may be followed by a tab character
+// and a description of the value.
+const FlagEnumValues = "cobra_annotation_flag_enum_values"
+
+// enumValue wraps the pflag.Value of a flag to reject any value that is not
+// part of the allowed set.
+type enumValue struct {
+	pflag.Value
+	allowed []string
+}
+
+func (e *enumValue) Set(val string) error {
+	values, err := enumValues(e.Value.Type(), val)
+	if err != nil {
+		return err
+	}
+	if err := e.check(values); err != nil {
+		return err
+	}
+	return e.Value.Set(val)
+}
+
+// enumValues returns the values held by val, the value of a flag of type typ,
+// read like pflag does: the string slices are CSV records, the other slices
+// are split on the commas.
+func enumValues(typ, val string) ([]string, error) {
+	switch {
+	case typ == "stringSlice":
+		if val == "" {
+			return nil, nil
+		}
+		return csv.NewReader(strings.NewReader(val)).Read()
+	case strings.HasSuffix(typ, "Slice"):
+		return strings.Split(val, ","), nil
+	}
+	return []string{val}, nil
+}
+
+// check returns an error if one of values is not allowed.
+func (e *enumValue) check(values []string) error {
+	for _, v := range values {
+		if !stringInSlice(v, e.allowed) {
+			return enumError(v, e.allowed)
+		}
+	}
+	return nil
+}
+
+// sliceValue is the pflag.SliceValue interface of the slice flags of the
+// recent versions of pflag.
+type sliceValue interface {
+	Append(value string) error
+	Replace(values []string) error
+	GetSlice() []string
+}
+
+// enumSliceValue is an enumValue wrapping a sliceValue, which keeps
+// implementing the interface.
+type enumSliceValue struct {
+	*enumValue
+}
+
+func (e enumSliceValue) Append(val string) error {
+	if err := e.check([]string{val}); err != nil {
+		return err
+	}
+	return e.Value.(sliceValue).Append(val)
+}
+
+func (e enumSliceValue) Replace(values []string) error {
+	if err := e.check(values); err != nil {
+		return err
+	}
+	return e.Value.(sliceValue).Replace(values)
+}
+
+func (e enumSliceValue) GetSlice() []string {
+	return e.Value.(sliceValue).GetSlice()
+}
+
+// asEnumValue returns the enumValue of a flag marked with MarkFlagEnum.
+func asEnumValue(value pflag.Value) (*enumValue, bool) {
+	switch v := value.(type) {
+	case *enumValue:
+		return v, true
+	case enumSliceValue:
+		return v.enumValue, true
+	}
+	return nil, false
+}
+
+// enumError returns the error describing that val is not one of allowed,
+// suggesting the closest allowed value if there is one.
+func enumError(val string, allowed []string) error {
+	msg := fmt.Sprintf("must be one of %s", quoteJoin(allowed))
+
+	suggestion := ""
+	minDistance := 3
+	for _, a := range allowed {
+		if d := ld(val, a, true); d < minDistance {
+			suggestion = a
+			minDistance = d
+		}
+	}
+	if suggestion == "" && val != "" {
+		for _, a := range allowed {
+			if strings.HasPrefix(strings.ToLower(a), strings.ToLower(val)) {
+				suggestion = a
+				break
+			}
+		}
+	}
+	if suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	}
+	return errors.New(msg)
+}
+
+func quoteJoin(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// MarkFlagEnum restricts the values accepted by the named flag to the specified values.
+// See MarkFlagEnum(flags, name, values...) for details.
+func (c *Command) MarkFlagEnum(name string, values ...string) error {
+	return MarkFlagEnum(c.Flags(), name, values...)
+}
+
+// MarkPersistentFlagEnum restricts the values accepted by the named persistent flag
+// to the specified values.
+// See MarkFlagEnum(flags, name, values...) for details.
+func (c *Command) MarkPersistentFlagEnum(name string, values ...string) error {
+	return MarkFlagEnum(c.PersistentFlags(), name, values...)
+}
+
+// MarkFlagEnum restricts the values accepted by the named flag to the specified values.
+// Each value can be followed by a tab character and a description of the value.
+// Any other value is rejected when the flags are parsed, and an error is returned
+// if the default value of the flag is not allowed, unless it is empty. The allowed values are
+// listed after the usage of the flag in the help (see EnumFlagUsage), and are
+// provided automatically as completion choices when no completion function was
+// registered for the flag. The usage of the flag is not changed.
+func MarkFlagEnum(flags *pflag.FlagSet, name string, values ...string) error {
+	flag := flags.Lookup(name)
+	if flag == nil {
+		return fmt.Errorf("MarkFlagEnum: flag '%s' does not exist", name)
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("MarkFlagEnum: no values specified for flag '%s'", name)
+	}
+
+	allowed := make([]string, len(values))
+	for i, v := range values {
+		allowed[i] = strings.SplitN(v, "\t", 2)[0]
+	}
+	defaults := []string{flag.DefValue}
+	if _, ok := flag.Value.(sliceValue); ok || strings.HasSuffix(flag.Value.Type(), "Slice") {
+		// The default of a slice is shown as "[a,b]"
+		var err error
+		if defaults, err = enumValues("stringSlice", strings.TrimSuffix(strings.TrimPrefix(flag.DefValue, "["), "]")); err != nil {
+			return fmt.Errorf("MarkFlagEnum: invalid default value for flag '%s': %v", name, err)
+		}
+	}
+	for _, d := range defaults {
+		if d != "" && !stringInSlice(d, allowed) {
+			return fmt.Errorf("MarkFlagEnum: default value %q of flag '%s' %v", d, name, enumError(d, allowed))
+		}
+	}
+
+	enum, ok := asEnumValue(flag.Value)
+	if !ok {
+		enum = &enumValue{Value: flag.Value}
+		if _, ok := flag.Value.(sliceValue); ok {
+			flag.Value = enumSliceValue{enum}
+		} else {
+			flag.Value = enum
+		}
+	}
+	enum.allowed = allowed
+	return flags.SetAnnotation(name, FlagEnumValues, values)
+}
+
+// EnumFlagUsage returns the usage of flag as shown in the help: followed by
+// the values it accepts if it was marked with MarkFlagEnum.
+func EnumFlagUsage(flag *pflag.Flag) string {
+	values, ok := flag.Annotations[FlagEnumValues]
+	if !ok {
+		return flag.Usage
+	}
+	allowed := make([]string, len(values))
+	for i, v := range values {
+		allowed[i] = strings.SplitN(v, "\t", 2)[0]
+	}
+	if flag.Usage == "" {
+		return fmt.Sprintf("one of: %s", strings.Join(allowed, ", "))
+	}
+	return fmt.Sprintf("%s (one of: %s)", flag.Usage, strings.Join(allowed, ", "))
+}
+
+// EnumFlagUsages is the same as flags.FlagUsages, but with the usages of
+// EnumFlagUsage. The default usage template lists the flags with it.
+func EnumFlagUsages(flags *pflag.FlagSet) string {
+	shown := pflag.NewFlagSet("", pflag.ContinueOnError)
+	shown.SortFlags = flags.SortFlags
+	flags.VisitAll(func(flag *pflag.Flag) {
+		f := *flag
+		f.Usage = EnumFlagUsage(flag)
+		shown.AddFlag(&f)
+	})
+	return shown.FlagUsages()
+}
+
+// enumFlagCompletionFunc returns a completion function providing the allowed
+// values of the flag, or nil if the flag is not an enum flag.
+func enumFlagCompletionFunc(flag *pflag.Flag) func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+	values, ok := flag.Annotations[FlagEnumValues]
+	if !ok {
+		return nil
+	}
+	return func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		var completions []string
+		for _, v := range values {
+			if strings.HasPrefix(v, toComplete) {
+				completions = append(completions, v)
+			}
+		}
+		return completions, BashCompDirectiveNoFileComp
+	}
+}
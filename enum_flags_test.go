@@ -0,0 +1,224 @@
+package cobra
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEnumFlagValidation(t *testing.T) {
+	var output string
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().StringVarP(&output, "output", "o", "json", "output format")
+	if err := rootCmd.MarkFlagEnum("output", "json\tJSON document", "yaml\tYAML document", "table"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := executeCommand(rootCmd, "--output", "yaml"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if output != "yaml" {
+		t.Errorf("expected output to be %q, got %q", "yaml", output)
+	}
+	if v, err := rootCmd.Flags().GetString("output"); err != nil || v != "yaml" {
+		t.Errorf("expected GetString to return %q, got %q (%v)", "yaml", v, err)
+	}
+
+	_, err := executeCommand(rootCmd, "-o", "jsn")
+	if err == nil {
+		t.Fatal("expected an error for a value outside of the enum")
+	}
+	checkStringContains(t, err.Error(), `must be one of "json", "yaml", "table"`)
+	checkStringContains(t, err.Error(), `(did you mean "json"?)`)
+
+	_, err = executeCommand(rootCmd, "--output=csv")
+	if err == nil {
+		t.Fatal("expected an error for a value outside of the enum")
+	}
+	checkStringOmits(t, err.Error(), "did you mean")
+
+	if err := rootCmd.MarkFlagEnum("unknown", "a"); err == nil {
+		t.Error("expected an error when marking an unknown flag")
+	}
+}
+
+func TestEnumSliceFlagValidation(t *testing.T) {
+	var labels []string
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().StringSliceVar(&labels, "labels", nil, "labels to apply")
+	if err := rootCmd.MarkFlagEnum("labels", "red", "green", "blue"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := executeCommand(rootCmd, "--labels", "red,blue"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Join(labels, ",") != "red,blue" {
+		t.Errorf("expected labels to be red,blue, got %v", labels)
+	}
+
+	if _, err := executeCommand(rootCmd, "--labels", "red,purple"); err == nil {
+		t.Error("expected an error for a value outside of the enum")
+	}
+}
+
+func TestEnumFlagHelp(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().String("output", "json", "output format")
+	rootCmd.MarkFlagEnum("output", "json", "yaml")
+	// Marking again replaces the allowed values
+	rootCmd.MarkFlagEnum("output", "json", "yaml", "table")
+
+	output, err := executeCommand(rootCmd, "--help")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, "output format (one of: json, yaml, table)")
+	if usage := rootCmd.Flag("output").Usage; usage != "output format" {
+		t.Errorf("expected the usage of the flag to be unchanged, got %q", usage)
+	}
+}
+
+// testSliceValue is a pflag.Value implementing the SliceValue interface of
+// the recent versions of pflag.
+type testSliceValue struct {
+	values []string
+}
+
+func (v *testSliceValue) String() string                { return "[" + strings.Join(v.values, ",") + "]" }
+func (v *testSliceValue) Set(val string) error          { return v.Append(val) }
+func (v *testSliceValue) Type() string                  { return "testSlice" }
+func (v *testSliceValue) Append(val string) error       { v.values = append(v.values, val); return nil }
+func (v *testSliceValue) Replace(values []string) error { v.values = values; return nil }
+func (v *testSliceValue) GetSlice() []string            { return v.values }
+
+func TestEnumFlagSliceValue(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().Var(&testSliceValue{}, "colors", "colors")
+	if err := rootCmd.MarkFlagEnum("colors", "red", "green"); err != nil {
+		t.Fatal(err)
+	}
+
+	slice, ok := rootCmd.Flag("colors").Value.(sliceValue)
+	if !ok {
+		t.Fatal("expected the enum flag to keep implementing SliceValue")
+	}
+	if err := slice.Append("red"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := slice.Append("blue"); err == nil {
+		t.Error("expected an error for a value outside of the enum")
+	}
+	if err := slice.Replace([]string{"green", "purple"}); err == nil {
+		t.Error("expected an error for a value outside of the enum")
+	}
+	if got := strings.Join(slice.GetSlice(), ","); got != "red" {
+		t.Errorf("expected the slice to be red, got %q", got)
+	}
+
+	// Marking again keeps a single wrapper
+	if err := rootCmd.MarkFlagEnum("colors", "red", "green", "blue"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootCmd.Flag("colors").Value.(sliceValue).Append("blue"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestEnumFlagCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.PersistentFlags().String("output", "json", "output format")
+	rootCmd.MarkPersistentFlagEnum("output", "json\tJSON document", "yaml\tYAML document")
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+
+	output, err := executeCommand(rootCmd, CompRequestCmd, "child", "--output", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"json\tJSON document",
+		"yaml\tYAML document",
+		":4",
+		"Completion ended with directive: BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	output, err = executeCommand(rootCmd, CompNoDescRequestCmd, "child", "--output=y")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"yaml",
+		":4",
+		"Completion ended with directive: BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestEnumSliceFlagCSV(t *testing.T) {
+	var labels []string
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().StringSliceVar(&labels, "labels", nil, "labels to apply")
+	if err := rootCmd.MarkFlagEnum("labels", "red", "dark,blue"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The values are read as CSV, like pflag does
+	if _, err := executeCommand(rootCmd, "--labels", `red,"dark,blue"`); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(labels) != 2 || labels[1] != "dark,blue" {
+		t.Errorf("expected labels to be [red dark,blue], got %q", labels)
+	}
+	if _, err := executeCommand(rootCmd, "--labels", "red,dark"); err == nil {
+		t.Error("expected an error for a value outside of the enum")
+	}
+}
+
+func TestEnumFlagDefault(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().String("output", "xml", "output format")
+	rootCmd.Flags().String("format", "", "format")
+	rootCmd.Flags().StringSlice("labels", []string{"red", "purple"}, "labels")
+
+	err := rootCmd.MarkFlagEnum("output", "json", "yaml")
+	if err == nil {
+		t.Fatal("expected an error for a default value outside of the enum")
+	}
+	checkStringContains(t, err.Error(), `default value "xml"`)
+	if err := rootCmd.MarkFlagEnum("labels", "red", "green"); err == nil {
+		t.Error("expected an error for a default value outside of the enum")
+	}
+	// An empty default means the flag is not set
+	if err := rootCmd.MarkFlagEnum("format", "json", "yaml"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestEnumFlagBashCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.PersistentFlags().String("color", "", "color")
+	if err := rootCmd.MarkPersistentFlagEnum("color", "red", "green"); err != nil {
+		t.Fatal(err)
+	}
+	childCmd := &Command{Use: "child", Run: emptyRun}
+	rootCmd.AddCommand(childCmd)
+
+	buf := new(bytes.Buffer)
+	if err := rootCmd.GenBashCompletion(buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	// The enum values are requested from the Go code, for the subcommands as well
+	expected := `    flags_with_completion+=("--color")
+    flags_completion+=("__root_handle_go_custom_completion")
+`
+	checkStringContains(t, output, "_root_child()\n")
+	if strings.Count(output, expected) != 2 {
+		t.Errorf("expected the completion of --color for both commands, got:\n%s", output)
+	}
+}
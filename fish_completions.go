@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 )
 
 func genFishComp(buf *bytes.Buffer, name string, includeDesc bool) {
@@ -21,6 +20,13 @@ function __%[1]s_debug
     end
 end
 
+# Unescape a field of the __complete output, where a backslash is
+# printed as \\, a tab as \t and a newline as \n
+function __%[1]s_unescape
+    set parts (string split -- '\\\\' $argv[1])
+    string join -- '\\' (string replace -a -- '\t' \t $parts | string replace -a -- '\n' \n)
+end
+
 function __%[1]s_perform_completion
     __%[1]s_debug "Starting __%[1]s_perform_completion with: $argv"
 
@@ -40,6 +46,9 @@ function __%[1]s_perform_completion
     set requestComp "$args[1] %[2]s $args[2..-1] $emptyArg"
     __%[1]s_debug "Calling $requestComp"
 
+    # Request the completions with their group and kind, in format 3
+    # where the fields are escaped
+    set -lx COBRA_COMPLETION_FORMAT 3
     set results (eval $requestComp 2> /dev/null)
     set comps $results[1..-2]
     set directiveLine $results[-1]
@@ -53,7 +62,27 @@ function __%[1]s_perform_completion
     __%[1]s_debug "flagPrefix: $flagPrefix"
 
     for comp in $comps
-        printf "%%s%%s\n" "$flagPrefix" "$comp"
+        # Each completion holds its value, description, group and kind separated by tabs.
+        # Within each field, backslashes, tabs and newlines are escaped.
+        set fields (string split -- \t "$comp")
+        if string match -q -r -- '\\\\[tn]' (string replace -a -- '\\\\' '' "$fields[1]")
+            # Fish lists one completion per line and splits the description at
+            # the first tab: such a value cannot be offered
+            __%[1]s_debug "Skipping value with a tab or a newline: $fields[1]"
+            continue
+        end
+        set value (__%[1]s_unescape "$fields[1]")
+        # Fish shows the group along with the description, on a single line
+        set desc (__%[1]s_unescape "$fields[2]" | string join " " | string replace -a -- \t " ")
+        set group (__%[1]s_unescape "$fields[3]" | string join " ")
+        if test -n "$desc"; and test -n "$group"
+            set desc "$desc ($group)"
+        end
+        if test -n "$desc"
+            printf "%%s%%s\t%%s\n" "$flagPrefix" "$value" "$desc"
+        else
+            printf "%%s%%s\n" "$flagPrefix" "$value"
+        end
     end
 
     printf "%%s\n" "$directiveLine"
@@ -104,6 +133,21 @@ function __%[1]s_prepare_completions
         return 0
     end
 
+    set timeout (math (math --scale 0 $directive / %[6]d) %% 2)
+    if test $timeout -eq 1
+        __%[1]s_debug "Completion timed out, using the completions gathered so far"
+    end
+
+    set filefilter (math (math --scale 0 $directive / %[7]d) %% 2)
+    set dirfilter (math (math --scale 0 $directive / %[8]d) %% 2)
+    if test $filefilter -eq 1; or test $dirfilter -eq 1
+        __%[1]s_debug "File extension filtering or directory filtering not supported"
+        # Do full file completion instead
+        set --global __%[1]s_comp_do_file_comp 1
+        set --global __%[1]s_comp_results
+        return 0
+    end
+
     set nospace (math (math --scale 0 $directive / %[4]d) %% 2)
     set nofiles (math (math --scale 0 $directive / %[5]d) %% 2)
 
@@ -149,7 +193,8 @@ complete -c %[1]s -n 'set --query __%[1]s_comp_do_file_comp'
 # It provides the program's completion choices.
 complete -c %[1]s -n '__%[1]s_prepare_completions' -f -a '$__%[1]s_comp_results'
 
-`, name, compCmd, BashCompDirectiveError, BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp))
+`, name, compCmd, BashCompDirectiveError, BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp, BashCompDirectiveTimeout,
+		BashCompDirectiveFilterFileExt, BashCompDirectiveFilterDirs))
 }
 
 // GenFishCompletion generates fish completion file and writes to the passed writer.
@@ -162,11 +207,12 @@ func (c *Command) GenFishCompletion(w io.Writer, includeDesc bool) error {
 
 // GenFishCompletionFile generates fish completion file.
 func (c *Command) GenFishCompletionFile(filename string, includeDesc bool) error {
-	outFile, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
+	return c.GenFishCompletionFS(DirFS(""), filename, includeDesc)
+}
 
-	return c.GenFishCompletion(outFile, includeDesc)
+// GenFishCompletionFS generates the fish completion file name in fs.
+func (c *Command) GenFishCompletionFS(fs WriteFS, name string, includeDesc bool) error {
+	return WriteFile(fs, name, func(w io.Writer) error {
+		return c.GenFishCompletion(w, includeDesc)
+	})
 }
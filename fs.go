@@ -0,0 +1,201 @@
+package cobra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WriteFS is a filesystem the completion scripts and the documentation are
+// written to. A file is complete once the writer Create returns is closed.
+type WriteFS interface {
+	Create(name string) (io.WriteCloser, error)
+}
+
+// DirFS returns a WriteFS creating the files in dir, or relative to the
+// current directory if dir is empty. The files are created with os.Create.
+func DirFS(dir string) WriteFS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+func (dir dirFS) Create(name string) (io.WriteCloser, error) {
+	return os.Create(filepath.Join(string(dir), name))
+}
+
+// WriteFile writes the file name of fs with gen, and closes it.
+func WriteFile(fs WriteFS, name string, gen func(io.Writer) error) error {
+	f, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := gen(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// bufferedFile is a file whose content is passed to save when it is closed.
+type bufferedFile struct {
+	bytes.Buffer
+	save func([]byte) error
+}
+
+func (f *bufferedFile) Close() error {
+	return f.save(f.Bytes())
+}
+
+// MemFS is a WriteFS keeping the files in memory, e.g. for tests.
+// It is safe for concurrent use.
+type MemFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{files: make(map[string][]byte)}
+}
+
+// Create creates or truncates the file name. Its content is saved when it is closed.
+func (fs *MemFS) Create(name string) (io.WriteCloser, error) {
+	name = filepath.ToSlash(filepath.Clean(name))
+	fs.mu.Lock()
+	fs.files[name] = nil
+	fs.mu.Unlock()
+	return &bufferedFile{save: func(content []byte) error {
+		fs.mu.Lock()
+		fs.files[name] = content
+		fs.mu.Unlock()
+		return nil
+	}}, nil
+}
+
+// ReadFile returns the content of the file name, or an error satisfying
+// os.IsNotExist if it was not created.
+func (fs *MemFS) ReadFile(name string) ([]byte, error) {
+	name = filepath.ToSlash(filepath.Clean(name))
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	content, ok := fs.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return content, nil
+}
+
+// Names returns the sorted names of the files created.
+func (fs *MemFS) Names() []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	names := make([]string, 0, len(fs.files))
+	for name := range fs.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ZipFS is a WriteFS adding the files to a zip archive, in the order they
+// are closed. Close must be called once all the files are written.
+type ZipFS struct {
+	// ModTime is the modification time of the files, the time they are
+	// closed if zero.
+	ModTime time.Time
+
+	mu sync.Mutex
+	w  *zip.Writer
+}
+
+// NewZipFS returns a ZipFS writing the archive to w.
+func NewZipFS(w io.Writer) *ZipFS {
+	return &ZipFS{w: zip.NewWriter(w)}
+}
+
+// Create returns the file name, which is added to the archive when it is closed.
+func (fs *ZipFS) Create(name string) (io.WriteCloser, error) {
+	return &bufferedFile{save: func(content []byte) error {
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(name),
+			Method:   zip.Deflate,
+			Modified: archiveModTime(fs.ModTime),
+		}
+		header.SetMode(0644)
+
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		w, err := fs.w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}}, nil
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (fs *ZipFS) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.w.Close()
+}
+
+// TarFS is a WriteFS adding the files to a tar archive, in the order they
+// are closed. Close must be called once all the files are written.
+type TarFS struct {
+	// ModTime is the modification time of the files, the time they are
+	// closed if zero.
+	ModTime time.Time
+
+	mu sync.Mutex
+	w  *tar.Writer
+}
+
+// NewTarFS returns a TarFS writing the archive to w. The archive may be
+// compressed by passing a gzip.Writer.
+func NewTarFS(w io.Writer) *TarFS {
+	return &TarFS{w: tar.NewWriter(w)}
+}
+
+// Create returns the file name, which is added to the archive when it is closed.
+func (fs *TarFS) Create(name string) (io.WriteCloser, error) {
+	return &bufferedFile{save: func(content []byte) error {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     filepath.ToSlash(name),
+			Mode:     0644,
+			Size:     int64(len(content)),
+			ModTime:  archiveModTime(fs.ModTime),
+		}
+
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		if err := fs.w.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := fs.w.Write(content)
+		return err
+	}}, nil
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (fs *TarFS) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.w.Close()
+}
+
+func archiveModTime(modTime time.Time) time.Time {
+	if modTime.IsZero() {
+		return time.Now()
+	}
+	return modTime
+}
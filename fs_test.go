@@ -0,0 +1,165 @@
+package cobra
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestFiles writes the files "a.txt" and "dir/b.txt" to fs, closing
+// "a.txt" last.
+func writeTestFiles(t *testing.T, fs WriteFS) {
+	a, err := fs.Create("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(fs, "dir/b.txt", func(w io.Writer) error {
+		_, err := io.WriteString(w, "b")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(a, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cobra-dir-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, DirFS(dir))
+
+	for name, expected := range map[string]string{"a.txt": "a", "dir/b.txt": "b"} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected %q in %s, got %q", expected, name, content)
+		}
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	fs := NewMemFS()
+	writeTestFiles(t, fs)
+
+	if names := strings.Join(fs.Names(), " "); names != "a.txt dir/b.txt" {
+		t.Errorf("Expected files %q, got %q", "a.txt dir/b.txt", names)
+	}
+	content, err := fs.ReadFile("dir/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "b" {
+		t.Errorf("Expected %q, got %q", "b", content)
+	}
+	if _, err := fs.ReadFile("c.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+}
+
+func TestZipFS(t *testing.T) {
+	buf := new(bytes.Buffer)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs := NewZipFS(buf)
+	fs.ModTime = modTime
+	writeTestFiles(t, fs)
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, f.Name+"="+string(content))
+		if !f.Modified.Equal(modTime) {
+			t.Errorf("Expected %s to be modified at %v, got %v", f.Name, modTime, f.Modified)
+		}
+	}
+	if expected := "dir/b.txt=b a.txt=a"; strings.Join(got, " ") != expected {
+		t.Errorf("Expected files %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func TestTarFS(t *testing.T) {
+	buf := new(bytes.Buffer)
+	fs := NewTarFS(buf)
+	writeTestFiles(t, fs)
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := tar.NewReader(buf)
+	var got []string
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, header.Name+"="+string(content))
+	}
+	if expected := "dir/b.txt=b a.txt=a"; strings.Join(got, " ") != expected {
+		t.Errorf("Expected files %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func TestGenCompletionFS(t *testing.T) {
+	c := &Command{Use: "root", Run: emptyRun}
+	fs := NewMemFS()
+	if err := c.GenBashCompletionFS(fs, "root.bash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GenZshCompletionFSV2(fs, "_root", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GenFishCompletionFS(fs, "root.fish", true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"root.bash", "_root", "root.fish"} {
+		content, err := fs.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "root") {
+			t.Errorf("Expected the completion of root in %s, got %q", name, content)
+		}
+	}
+}
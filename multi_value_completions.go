@@ -0,0 +1,92 @@
+package cobra
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// ChosenValues returns, while a completion function is being called, the values
+// already chosen for the slice flag or the variadic arguments being completed.
+// For a slice flag, these are the comma-separated values preceding the value
+// being completed (e.g., "a" and "b" for "--labels=a,b,<TAB>").  For a command
+// with VariadicValidArgs, these are the arguments already on the command-line.
+func (c *Command) ChosenValues() []string {
+	if state := completionStateOf(c); state != nil {
+		return state.chosenValues()
+	}
+	return nil
+}
+
+// isMultiValueFlag returns true if the flag accepts a comma-separated list of values.
+// The values of array flags, such as StringArray, are not split by pflag: each
+// occurrence of the flag is a single value, which may contain commas.
+func isMultiValueFlag(flag *pflag.Flag) bool {
+	return strings.HasSuffix(flag.Value.Type(), "Slice")
+}
+
+// splitMultiValue splits the word being completed for a slice flag into the
+// prefix holding the values already chosen, those values, and the value being completed.
+func splitMultiValue(toComplete string) (prefix string, chosen []string, current string) {
+	i := strings.LastIndex(toComplete, ",")
+	if i < 0 {
+		return "", nil, toComplete
+	}
+	return toComplete[:i+1], strings.Split(toComplete[:i], ","), toComplete[i+1:]
+}
+
+// removeChosen returns the completions whose value is not part of chosen.
+func removeChosen(comps []string, chosen []string) []string {
+	var result []string
+	for _, comp := range comps {
+		if !stringInSlice(strings.SplitN(comp, "\t", 2)[0], chosen) {
+			result = append(result, comp)
+		}
+	}
+	return result
+}
+
+// multiValueCompletionFunc wraps the completion function of a slice flag so that
+// it only receives the value being completed, with the values already chosen
+// available through ChosenValues().  Its completions are filtered of the values
+// already chosen and prefixed with them, and no space is added after them so
+// that the list can be continued, starting from the first value.
+func multiValueCompletionFunc(completionFn func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective)) func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+	return func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		prefix, chosen, current := splitMultiValue(toComplete)
+		addPrefix := func(comps []string) []string {
+			comps = removeChosen(comps, chosen)
+			for i := range comps {
+				comps[i] = prefix + comps[i]
+			}
+			return comps
+		}
+		if state := completionStateOf(cmd); state != nil {
+			state.setChosen(chosen, addPrefix)
+		}
+
+		comps, directive := completionFn(cmd, args, current)
+		if directive&BashCompDirectiveError != 0 {
+			return comps, directive
+		}
+
+		// Allow the user to add a comma and continue the list
+		return addPrefix(comps), directive | BashCompDirectiveNoSpace
+	}
+}
+
+// variadicArgsCompletionFunc wraps the ValidArgsFunction of a command with
+// VariadicValidArgs so that the arguments already on the command-line are
+// not offered again.
+func variadicArgsCompletionFunc(completionFn func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective)) func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+	return func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		if state := completionStateOf(cmd); state != nil {
+			state.setChosen(args, func(comps []string) []string {
+				return removeChosen(comps, args)
+			})
+		}
+
+		comps, directive := completionFn(cmd, args, toComplete)
+		return removeChosen(comps, args), directive
+	}
+}
@@ -0,0 +1,163 @@
+package cobra
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSliceFlagCompletion(t *testing.T) {
+	var chosen []string
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().StringSlice("labels", nil, "labels to apply")
+	rootCmd.RegisterFlagCompletionFunc("labels", func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		chosen = cmd.ChosenValues()
+		var comps []string
+		for _, comp := range []string{"red\tThe red label", "green\tThe green label", "blue\tThe blue label"} {
+			if strings.HasPrefix(comp, toComplete) {
+				comps = append(comps, comp)
+			}
+		}
+		return comps, BashCompDirectiveNoFileComp
+	})
+
+	// The first value can be followed by a comma as well
+	output, err := executeCommand(rootCmd, CompRequestCmd, "--labels", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"red\tThe red label",
+		"green\tThe green label",
+		"blue\tThe blue label",
+		":6",
+		"Completion ended with directive: BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// Values already chosen are passed to the function, removed from the
+	// completions and prepended to them
+	output, err = executeCommand(rootCmd, CompRequestCmd, "--labels=red,")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"red,green\tThe green label",
+		"red,blue\tThe blue label",
+		":6",
+		"Completion ended with directive: BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+	if strings.Join(chosen, ",") != "red" {
+		t.Errorf("expected the chosen values to be [red], got %v", chosen)
+	}
+
+	output, err = executeCommand(rootCmd, CompNoDescRequestCmd, "--labels", "blue,red,g")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"blue,red,green",
+		":6",
+		"Completion ended with directive: BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestArrayFlagCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().StringArray("set", nil, "values to set")
+	rootCmd.RegisterFlagCompletionFunc("set", func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		return []string{toComplete + "=value"}, BashCompDirectiveNoFileComp
+	})
+
+	// The values of array flags are not split on commas
+	output, err := executeCommand(rootCmd, CompNoDescRequestCmd, "--set", "a,b")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"a,b=value",
+		":4",
+		"Completion ended with directive: BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestSliceEnumFlagCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	rootCmd.Flags().StringSlice("labels", nil, "labels to apply")
+	rootCmd.MarkFlagEnum("labels", "red", "green", "blue")
+
+	output, err := executeCommand(rootCmd, CompRequestCmd, "--labels", "green,")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"green,red",
+		"green,blue",
+		":6",
+		"Completion ended with directive: BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+}
+
+func TestVariadicArgsCompletion(t *testing.T) {
+	rootCmd := &Command{
+		Use:               "root",
+		Args:              ArbitraryArgs,
+		VariadicValidArgs: true,
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+			if strings.Join(cmd.ChosenValues(), ",") != strings.Join(args, ",") {
+				return nil, BashCompDirectiveError
+			}
+			return []string{"one", "two", "three"}, BashCompDirectiveNoFileComp
+		},
+		Run: emptyRun,
+	}
+
+	output, err := executeCommand(rootCmd, CompNoDescRequestCmd, "two", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := strings.Join([]string{
+		"one",
+		"three",
+		":4",
+		"Completion ended with directive: BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	rootCmd = &Command{
+		Use:               "root",
+		Args:              ArbitraryArgs,
+		VariadicValidArgs: true,
+		ValidArgs:         []string{"one", "two", "three"},
+		Run:               emptyRun,
+	}
+	output, err = executeCommand(rootCmd, CompNoDescRequestCmd, "one", "three", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected = strings.Join([]string{
+		"two",
+		":4",
+		"Completion ended with directive: BashCompDirectiveNoFileComp", ""}, "\n")
+	if output != expected {
+		t.Errorf("expected: %q, got: %q", expected, output)
+	}
+
+	// In bash, the ValidArgs are completed by the Go code to be filtered as well
+	buf := new(bytes.Buffer)
+	if err := rootCmd.GenBashCompletion(buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "    has_completion_function=1\n")
+	checkStringOmits(t, buf.String(), "must_have_one_noun+=")
+}
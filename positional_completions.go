@@ -0,0 +1,89 @@
+package cobra
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PositionalArgsRest is the argument position to use to register the
+// completion of every positional argument that has no completion registered
+// for its own position.
+const PositionalArgsRest = 0
+
+// RegisterPositionalArgCompletionFunc registers a function to provide completion
+// for the positional argument at the specified position (first argument is 1),
+// or for all remaining arguments when the position is PositionalArgsRest.
+// A completion registered for a position takes precedence over ValidArgsFunction
+// and ValidArgs and is honored by every completion script relying on __complete
+// (bash, zsh V2, fish and PowerShell).
+func (c *Command) RegisterPositionalArgCompletionFunc(argPosition int, f func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective)) error {
+	if argPosition < 0 {
+		return fmt.Errorf("invalid argument position (%d)", argPosition)
+	}
+	if _, exists := c.argCompletions[argPosition]; exists {
+		return fmt.Errorf("RegisterPositionalArgCompletionFunc: positional argument %d already registered", argPosition)
+	}
+	if c.argCompletions == nil {
+		c.argCompletions = make(map[int]func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective))
+	}
+	c.argCompletions[argPosition] = f
+	return nil
+}
+
+// MarkPositionalArgWords marks the positional argument at the specified position
+// (first argument is 1, or PositionalArgsRest) as completed by the provided words.
+// Each word can be followed by a TAB and a description, as for ValidArgs.
+func (c *Command) MarkPositionalArgWords(argPosition int, words ...string) error {
+	if len(words) == 0 {
+		return fmt.Errorf("trying to set empty word list for positional argument %d", argPosition)
+	}
+	return c.RegisterPositionalArgCompletionFunc(argPosition, func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		var completions []string
+		for _, word := range words {
+			if strings.HasPrefix(word, toComplete) {
+				completions = append(completions, word)
+			}
+		}
+		return completions, BashCompDirectiveNoFileComp
+	})
+}
+
+// MarkPositionalArgFilename marks the positional argument at the specified position
+// (first argument is 1, or PositionalArgsRest) as completed by file selection.
+// extensions (e.g. "yaml" or "*.yaml") are optional - if not provided the
+// completion will search for all files.  Other patterns, such as "data-*.json",
+// are rejected as the shells can only filter files by extension.
+func (c *Command) MarkPositionalArgFilename(argPosition int, extensions ...string) error {
+	var filters []string
+	for _, ext := range extensions {
+		filter := strings.TrimPrefix(ext, "*.")
+		if filter == "" || strings.ContainsAny(filter, "*?[]{}/\\") {
+			return fmt.Errorf("invalid extension %q for positional argument %d: only extensions and patterns of the form *.ext are supported", ext, argPosition)
+		}
+		filters = append(filters, filter)
+	}
+	return c.RegisterPositionalArgCompletionFunc(argPosition, func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		if len(filters) == 0 {
+			return nil, BashCompDirectiveDefault
+		}
+		return filters, BashCompDirectiveFilterFileExt
+	})
+}
+
+// MarkPositionalArgDirname marks the positional argument at the specified position
+// (first argument is 1, or PositionalArgsRest) as completed by directory names.
+func (c *Command) MarkPositionalArgDirname(argPosition int) error {
+	return c.RegisterPositionalArgCompletionFunc(argPosition, func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+		return nil, BashCompDirectiveFilterDirs
+	})
+}
+
+// positionalArgCompletionFunc returns the completion function registered for
+// the positional argument at argPosition, falling back to the one registered
+// for PositionalArgsRest, or nil if there is none.
+func (c *Command) positionalArgCompletionFunc(argPosition int) func(cmd *Command, args []string, toComplete string) ([]string, BashCompDirective) {
+	if f, ok := c.argCompletions[argPosition]; ok {
+		return f
+	}
+	return c.argCompletions[PositionalArgsRest]
+}
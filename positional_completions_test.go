@@ -0,0 +1,98 @@
+package cobra
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPositionalArgCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "root", Run: emptyRun}
+	childCmd := &Command{
+		Use:       "child",
+		Args:      ArbitraryArgs,
+		ValidArgs: []string{"valid"},
+		Run:       emptyRun,
+	}
+	rootCmd.AddCommand(childCmd)
+	if err := childCmd.MarkPositionalArgWords(1, "one\tThe first", "other"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := childCmd.MarkPositionalArgFilename(2, "*.yaml", "json"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := childCmd.MarkPositionalArgDirname(PositionalArgsRest); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := childCmd.MarkPositionalArgDirname(1); err == nil {
+		t.Error("Expected an error when registering the same position twice")
+	}
+
+	testcases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"o"}, []string{
+			"one\tThe first",
+			"other",
+			":4",
+			"Completion ended with directive: BashCompDirectiveNoFileComp", ""}},
+		{[]string{"one", ""}, []string{
+			"yaml",
+			"json",
+			":8",
+			"Completion ended with directive: BashCompDirectiveFilterFileExt", ""}},
+		{[]string{"one", "a.yaml", "dir", ""}, []string{
+			":16",
+			"Completion ended with directive: BashCompDirectiveFilterDirs", ""}},
+	}
+	for _, tc := range testcases {
+		output, err := executeCommand(rootCmd, append([]string{CompRequestCmd, "child"}, tc.args...)...)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		expected := strings.Join(tc.expected, "\n")
+		if output != expected {
+			t.Errorf("%v: expected: %q, got: %q", tc.args, expected, output)
+		}
+	}
+
+	// Bash requests the arguments from the Go code rather than completing the ValidArgs
+	buf := new(bytes.Buffer)
+	if err := rootCmd.GenBashCompletion(buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), "    has_completion_function=1\n")
+	checkStringOmits(t, buf.String(), `must_have_one_noun+=("valid")`)
+}
+
+func TestPositionalArgFilenameInvalidExtension(t *testing.T) {
+	c := &Command{Use: "c", Run: emptyRun}
+	for _, ext := range []string{"", "*.", "data-*.json", "*.{yaml,yml}", "conf/*.yaml"} {
+		if err := c.MarkPositionalArgFilename(1, ext); err == nil {
+			t.Errorf("Expected an error for extension %q", ext)
+		}
+	}
+	if err := c.MarkPositionalArgFilename(1, "*.yaml", "yml"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestZshPositionalArgCompletionZshOnly(t *testing.T) {
+	rootCmd := &Command{Use: "root", Args: ArbitraryArgs, Run: emptyRun}
+	if err := rootCmd.MarkZshCompPositionalArgumentWords(1, "alpha", "beta"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The zsh markers are only used by the V1 zsh script
+	output, err := executeCommand(rootCmd, CompRequestCmd, "b")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringOmits(t, output, "beta")
+
+	// and a positional completion can still be registered for the other shells
+	if err := rootCmd.MarkPositionalArgWords(1, "beta"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -1,6 +1,3 @@
-// PowerShell completions are based on the amazing work from clap:
-// https://github.com/clap-rs/clap/blob/3294d18efe5f264d12c9035f404c7d189d4824e1/src/completions/powershell.rs
-//
 // The generated scripts require PowerShell v5.0+ (which comes Windows 10, but
 // can be downloaded separately for windows 7 or 8.1).
 
@@ -10,91 +7,251 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
-	"strings"
-
-	"github.com/spf13/pflag"
 )
 
-var powerShellCompletionTemplate = `using namespace System.Management.Automation
-using namespace System.Management.Automation.Language
-Register-ArgumentCompleter -Native -CommandName '%s' -ScriptBlock {
-    param($wordToComplete, $commandAst, $cursorPosition)
-    $commandElements = $commandAst.CommandElements
-    $command = @(
-        '%s'
-        for ($i = 1; $i -lt $commandElements.Count; $i++) {
-            $element = $commandElements[$i]
-            if ($element -isnot [StringConstantExpressionAst] -or
-                $element.StringConstantType -ne [StringConstantType]::BareWord -or
-                $element.Value.StartsWith('-')) {
-                break
-            }
-            $element.Value
+func genPowerShellComp(buf *bytes.Buffer, name string) {
+	buf.WriteString(fmt.Sprintf("# powershell completion for %-36s -*- shell-script -*-\n", name))
+	buf.WriteString(fmt.Sprintf(`
+function __%[1]s_debug {
+    if ($env:BASH_COMP_DEBUG_FILE) {
+        "$args" | Out-File -Append -FilePath "$env:BASH_COMP_DEBUG_FILE"
+    }
+}
+
+filter __%[1]s_escapeStringWithSpecialChars {
+    # Quote the values holding a character PowerShell could interpret: within
+    # single quotes, only the single quote itself needs to be doubled
+    if ($_ -match '[^\w./:=+-]') {
+        "'" + ($_ -replace "'", "''") + "'"
+    } else {
+        $_
+    }
+}
+
+# Unescape a field of the __complete output, where a backslash is
+# printed as \\, a tab as \t and a newline as \n
+function __%[1]s_unescape {
+    param([string]$Field)
+    [regex]::Replace($Field, '\\(.)', {
+        param($Match)
+        switch -CaseSensitive ($Match.Groups[1].Value) {
+            't' { "`+"`"+`t" }
+            'n' { "`+"`"+`n" }
+            default { $_ }
         }
-    ) -join ';'
-    $completions = @(switch ($command) {%s
     })
-    $completions.Where{ $_.CompletionText -like "$wordToComplete*" } |
-        Sort-Object -Property ListItemText
-}`
-
-func generatePowerShellSubcommandCases(out io.Writer, cmd *Command, previousCommandName string) {
-	var cmdName string
-	if previousCommandName == "" {
-		cmdName = cmd.Name()
-	} else {
-		cmdName = fmt.Sprintf("%s;%s", previousCommandName, cmd.Name())
-	}
-
-	fmt.Fprintf(out, "\n        '%s' {", cmdName)
-
-	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
-		if nonCompletableFlag(flag) {
-			return
-		}
-		usage := escapeStringForPowerShell(flag.Usage)
-		if len(flag.Shorthand) > 0 {
-			fmt.Fprintf(out, "\n            [CompletionResult]::new('-%s', '%s', [CompletionResultType]::ParameterName, '%s')", flag.Shorthand, flag.Shorthand, usage)
-		}
-		fmt.Fprintf(out, "\n            [CompletionResult]::new('--%s', '%s', [CompletionResultType]::ParameterName, '%s')", flag.Name, flag.Name, usage)
-	})
+}
 
-	for _, subCmd := range cmd.Commands() {
-		usage := escapeStringForPowerShell(subCmd.Short)
-		fmt.Fprintf(out, "\n            [CompletionResult]::new('%s', '%s', [CompletionResultType]::ParameterValue, '%s')", subCmd.Name(), subCmd.Name(), usage)
-	}
+Register-ArgumentCompleter -Native -CommandName '%[1]s' -ScriptBlock {
+    param(
+        $WordToComplete,
+        $CommandAst,
+        $CursorPosition
+    )
 
-	fmt.Fprint(out, "\n            break\n        }")
+    # Get the current command line and convert into a string
+    $Command = $CommandAst.CommandElements
+    $Command = "$Command"
 
-	for _, subCmd := range cmd.Commands() {
-		generatePowerShellSubcommandCases(out, subCmd, cmdName)
-	}
-}
+    __%[1]s_debug ""
+    __%[1]s_debug "========= starting completion logic =========="
+    __%[1]s_debug "WordToComplete: $WordToComplete Command: $Command CursorPosition: $CursorPosition"
+
+    # The user could have moved the cursor backwards on the command-line.
+    # We need to trigger completion from the $CursorPosition location, so we need
+    # to truncate the command-line ($Command) up to the $CursorPosition location.
+    # Make sure the $Command is longer then the $CursorPosition before we truncate.
+    # This happens because the $Command does not include the last space.
+    if ($Command.Length -gt $CursorPosition) {
+        $Command = $Command.Substring(0, $CursorPosition)
+    }
+    __%[1]s_debug "Truncated command: $Command"
+
+    $ShellCompDirectiveError = %[3]d
+    $ShellCompDirectiveNoSpace = %[4]d
+    $ShellCompDirectiveNoFileComp = %[5]d
+    $ShellCompDirectiveFilterFileExt = %[6]d
+    $ShellCompDirectiveFilterDirs = %[7]d
+    $ShellCompDirectiveTimeout = %[8]d
+
+    # Prepare the command to request completions for the program.
+    # Split the command at the first space to separate the program and arguments.
+    $Program, $Arguments = $Command.Split(" ", 2)
+    $RequestComp = "$Program %[2]s $Arguments"
+    __%[1]s_debug "RequestComp: $RequestComp"
+
+    # We cannot use $WordToComplete because it has the wrong value
+    # if the cursor was moved, so use the last argument
+    if ($WordToComplete -ne "") {
+        $WordToComplete = $Arguments.Split(" ")[-1]
+    }
+    __%[1]s_debug "New WordToComplete: $WordToComplete"
+
+    # Check for flag with equal sign
+    $IsEqualFlag = ($WordToComplete -Like "--*=*")
+    if ($IsEqualFlag) {
+        __%[1]s_debug "Completing equal sign flag"
+        # Remove the flag part
+        $Flag, $WordToComplete = $WordToComplete.Split("=", 2)
+    }
+
+    if ($WordToComplete -eq "" -And (-Not $IsEqualFlag)) {
+        # If the last parameter is complete (there is a space following it)
+        # we add an extra empty parameter so we can indicate this to the go method.
+        __%[1]s_debug "Adding extra empty parameter"
+        # We need to use `+"`"+`"`+"`"+`" to pass an empty argument, a "" or '' does not work
+        $RequestComp = "$RequestComp" + ' `+"`"+`"`+"`"+`"'
+    }
+
+    __%[1]s_debug "Calling $RequestComp"
+    # Request the completions with their group and kind, in format 3
+    # where the fields are escaped
+    $PreviousFormat = $env:COBRA_COMPLETION_FORMAT
+    $env:COBRA_COMPLETION_FORMAT = 3
+    # Call the command, storing the output in $Out and ignoring stderr.
+    # $Out is an array containing each line per element
+    Invoke-Expression -OutVariable Out "$RequestComp" 2>$null | Out-Null
+    $env:COBRA_COMPLETION_FORMAT = $PreviousFormat
+
+    # Get the directive from the last line
+    [int]$Directive = $Out[-1].TrimStart(':')
+    if ($Directive -eq "") {
+        # There is no directive specified
+        $Directive = 0
+    }
+    __%[1]s_debug "The completion directive is: $Directive"
+
+    # Remove the directive (last element) from the output
+    $Out = $Out | Select-Object -SkipLast 1
+    __%[1]s_debug "The completions are: $Out"
+
+    if (($Directive -band $ShellCompDirectiveError) -ne 0) {
+        # Error code.  No completion.
+        __%[1]s_debug "Received error from custom completion go code"
+        return
+    }
+
+    if (($Directive -band $ShellCompDirectiveTimeout) -ne 0) {
+        # The other directives still apply to the completions gathered so far
+        __%[1]s_debug "Completion timed out, using the completions gathered so far"
+    }
+
+    $Values = @($Out | ForEach-Object {
+        # Each completion holds its value, description, group and kind separated by tabs.
+        # Within each field, backslashes, tabs and newlines are escaped.
+        $Fields = $_.Split("`+"`"+`t")
+        $Name = __%[1]s_unescape $Fields[0]
+        $Description = if ($Fields.Count -gt 1) { __%[1]s_unescape $Fields[1] } else { "" }
+        $Group = if ($Fields.Count -gt 2) { __%[1]s_unescape $Fields[2] } else { "" }
+        if ($Description -and $Group) {
+            $Description = "$Description ($Group)"
+        }
+        __%[1]s_debug "Name: $Name Description: $Description"
 
-func escapeStringForPowerShell(s string) string {
-	return strings.Replace(s, "'", "''", -1)
+        # Set the description to a one space string if there is none set.
+        # This is needed because the CompletionResult does not accept an empty string as argument
+        if (-Not $Description) {
+            $Description = " "
+        }
+        @{Name = "$Name"; Description = "$Description"}
+    })
+
+    if (($Directive -band $ShellCompDirectiveFilterFileExt) -ne 0) {
+        # The completions are the file extensions to filter on
+        $Extensions = $Values | ForEach-Object { $_.Name }
+        __%[1]s_debug "Completing files with extensions: $Extensions"
+        $Parent = Split-Path -Parent "$WordToComplete"
+        Get-ChildItem -Path "$WordToComplete*" -ErrorAction SilentlyContinue | Where-Object {
+            $File = $_
+            $File.PSIsContainer -or ($Extensions | Where-Object { $File.Name -like "*.$_" })
+        } | ForEach-Object {
+            $Path = if ($Parent) { Join-Path $Parent $_.Name } else { $_.Name }
+            [System.Management.Automation.CompletionResult]::new($($Path | __%[1]s_escapeStringWithSpecialChars), "$Path", 'ProviderItem', "$Path")
+        }
+        return
+    }
+
+    if (($Directive -band $ShellCompDirectiveFilterDirs) -ne 0) {
+        # A completion, if any, is the directory within which to search
+        $Parent = Split-Path -Parent "$WordToComplete"
+        $Search = "$WordToComplete*"
+        if ($Values.Count -gt 0) {
+            $Parent = Join-Path $Values[0].Name $Parent
+            $Search = Join-Path $Values[0].Name $Search
+        }
+        __%[1]s_debug "Completing directories: $Search"
+        Get-ChildItem -Path $Search -Directory -ErrorAction SilentlyContinue | ForEach-Object {
+            $Path = if ($Parent) { Join-Path $Parent $_.Name } else { $_.Name }
+            [System.Management.Automation.CompletionResult]::new($($Path | __%[1]s_escapeStringWithSpecialChars), "$Path", 'ProviderContainer', "$Path")
+        }
+        return
+    }
+
+    $Space = " "
+    if (($Directive -band $ShellCompDirectiveNoSpace) -ne 0) {
+        __%[1]s_debug "ShellCompDirectiveNoSpace is called"
+        $Space = ""
+    }
+
+    $Values = @($Values | Where-Object {
+        # Filter the result, the word being completed is not a pattern
+        $_.Name -like "$([WildcardPattern]::Escape($WordToComplete))*"
+    } | ForEach-Object {
+        # Join the flag back if we have an equal sign flag
+        if ($IsEqualFlag) {
+            $_.Name = $Flag + "=" + $_.Name
+        }
+        $_
+    })
+
+    if ($Values.Count -eq 0) {
+        if (($Directive -band $ShellCompDirectiveNoFileComp) -ne 0) {
+            __%[1]s_debug "ShellCompDirectiveNoFileComp is called"
+            # Just print an empty string here so the shell does not start
+            # to complete paths.  We cannot use CompletionResult here because
+            # it does not accept an empty string as argument.
+            ""
+        }
+        # Otherwise, let PowerShell complete paths
+        return
+    }
+
+    $Values | ForEach-Object {
+        # CompletionResult Arguments:
+        # 1) CompletionText text to be used as the auto completion result
+        # 2) ListItemText   text to be displayed in the suggestion list
+        # 3) ResultType     type of completion result
+        # 4) ToolTip        text for the tooltip with details about the object
+        $CompletionText = $_.Name | __%[1]s_escapeStringWithSpecialChars
+        if ($Values.Count -eq 1) {
+            # Add the space of a single completion, as PowerShell does not
+            $CompletionText = "$CompletionText$Space"
+        }
+        [System.Management.Automation.CompletionResult]::new($CompletionText, "$($_.Name)", 'ParameterValue', "$($_.Description)")
+    }
+}
+`, name, CompRequestCmd, BashCompDirectiveError, BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp,
+		BashCompDirectiveFilterFileExt, BashCompDirectiveFilterDirs, BashCompDirectiveTimeout))
 }
 
 // GenPowerShellCompletion generates PowerShell completion file and writes to the passed writer.
+// The script requests the completions from the program through the hidden __complete command,
+// like the bash, zsh and fish scripts.
 func (c *Command) GenPowerShellCompletion(w io.Writer) error {
 	buf := new(bytes.Buffer)
-
-	var subCommandCases bytes.Buffer
-	generatePowerShellSubcommandCases(&subCommandCases, c, "")
-	fmt.Fprintf(buf, powerShellCompletionTemplate, c.Name(), c.Name(), subCommandCases.String())
-
+	genPowerShellComp(buf, c.Name())
 	_, err := buf.WriteTo(w)
 	return err
 }
 
 // GenPowerShellCompletionFile generates PowerShell completion file.
 func (c *Command) GenPowerShellCompletionFile(filename string) error {
-	outFile, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
+	return c.GenPowerShellCompletionFS(DirFS(""), filename)
+}
 
-	return c.GenPowerShellCompletion(outFile)
+// GenPowerShellCompletionFS generates the PowerShell completion file name in fs.
+func (c *Command) GenPowerShellCompletionFS(fs WriteFS, name string) error {
+	return WriteFile(fs, name, func(w io.Writer) error {
+		return c.GenPowerShellCompletion(w)
+	})
 }
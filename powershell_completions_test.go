@@ -2,22 +2,28 @@ package cobra
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 )
 
-func TestPowerShellCompletion(t *testing.T) {
+// The script no longer holds the command tree: the completions of the table
+// of the static generator are checked in the __complete output that the script
+// presents instead.
+func TestPowerShellCompletionRequests(t *testing.T) {
 	tcs := []struct {
 		name                string
 		root                *Command
+		args                []string
 		expectedExpressions []string
 	}{
 		{
 			name: "trivial",
-			root: &Command{Use: "trivialapp"},
+			root: &Command{Use: "trivialapp", Run: emptyRun},
+			args: []string{""},
+			// No completion, PowerShell completes the file names
 			expectedExpressions: []string{
-				"Register-ArgumentCompleter -Native -CommandName 'trivialapp' -ScriptBlock",
-				"$command = @(\n        'trivialapp'\n",
+				":0\n",
 			},
 		},
 		{
@@ -28,8 +34,8 @@ func TestPowerShellCompletion(t *testing.T) {
 				sub1 := &Command{Use: "sub1"}
 				r.AddCommand(sub1)
 
-				sub11 := &Command{Use: "sub11"}
-				sub12 := &Command{Use: "sub12"}
+				sub11 := &Command{Use: "sub11", Run: emptyRun}
+				sub12 := &Command{Use: "sub12", Run: emptyRun}
 
 				sub1.AddCommand(sub11)
 				sub1.AddCommand(sub12)
@@ -37,80 +43,68 @@ func TestPowerShellCompletion(t *testing.T) {
 				sub2 := &Command{Use: "sub2"}
 				r.AddCommand(sub2)
 
-				sub21 := &Command{Use: "sub21"}
-				sub22 := &Command{Use: "sub22"}
+				sub21 := &Command{Use: "sub21", Run: emptyRun}
+				sub22 := &Command{Use: "sub22", Run: emptyRun}
 
 				sub2.AddCommand(sub21)
 				sub2.AddCommand(sub22)
 
 				return r
 			}(),
+			args: []string{"sub1", ""},
 			expectedExpressions: []string{
-				"'tree'",
-				"[CompletionResult]::new('sub1', 'sub1', [CompletionResultType]::ParameterValue, '')",
-				"[CompletionResult]::new('sub2', 'sub2', [CompletionResultType]::ParameterValue, '')",
-				"'tree;sub1'",
-				"[CompletionResult]::new('sub11', 'sub11', [CompletionResultType]::ParameterValue, '')",
-				"[CompletionResult]::new('sub12', 'sub12', [CompletionResultType]::ParameterValue, '')",
-				"'tree;sub1;sub11'",
-				"'tree;sub1;sub12'",
-				"'tree;sub2'",
-				"[CompletionResult]::new('sub21', 'sub21', [CompletionResultType]::ParameterValue, '')",
-				"[CompletionResult]::new('sub22', 'sub22', [CompletionResultType]::ParameterValue, '')",
-				"'tree;sub2;sub21'",
-				"'tree;sub2;sub22'",
+				"sub11\n",
+				"sub12\n",
 			},
 		},
 		{
 			name: "flags",
 			root: func() *Command {
-				r := &Command{Use: "flags"}
+				r := &Command{Use: "flags", Run: emptyRun}
 				r.Flags().StringP("flag1", "a", "", "")
 				r.Flags().String("flag2", "", "")
 
-				sub1 := &Command{Use: "sub1"}
+				sub1 := &Command{Use: "sub1", Run: emptyRun}
 				sub1.Flags().StringP("flag3", "c", "", "")
 				r.AddCommand(sub1)
 
 				return r
 			}(),
+			args: []string{"-"},
 			expectedExpressions: []string{
-				"'flags'",
-				"[CompletionResult]::new('-a', 'a', [CompletionResultType]::ParameterName, '')",
-				"[CompletionResult]::new('--flag1', 'flag1', [CompletionResultType]::ParameterName, '')",
-				"[CompletionResult]::new('--flag2', 'flag2', [CompletionResultType]::ParameterName, '')",
-				"[CompletionResult]::new('sub1', 'sub1', [CompletionResultType]::ParameterValue, '')",
-				"'flags;sub1'",
-				"[CompletionResult]::new('-c', 'c', [CompletionResultType]::ParameterName, '')",
-				"[CompletionResult]::new('--flag3', 'flag3', [CompletionResultType]::ParameterName, '')",
+				"--flag1\n",
+				"-a\n",
+				"--flag2\n",
 			},
 		},
 		{
 			name: "usage",
 			root: func() *Command {
-				r := &Command{Use: "usage"}
+				r := &Command{Use: "usage", Run: emptyRun}
 				r.Flags().String("flag", "", "this describes the usage of the 'flag' flag")
 
 				sub1 := &Command{
 					Use:   "sub1",
 					Short: "short describes 'sub1'",
+					Run:   emptyRun,
 				}
 				r.AddCommand(sub1)
 
 				return r
 			}(),
+			args: []string{"s"},
 			expectedExpressions: []string{
-				"[CompletionResult]::new('--flag', 'flag', [CompletionResultType]::ParameterName, 'this describes the usage of the ''flag'' flag')",
-				"[CompletionResult]::new('sub1', 'sub1', [CompletionResultType]::ParameterValue, 'short describes ''sub1''')",
+				"sub1\tshort describes 'sub1'\n",
 			},
 		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			buf := new(bytes.Buffer)
-			tc.root.GenPowerShellCompletion(buf)
-			output := buf.String()
+			output, err := executeCommand(tc.root, append([]string{CompRequestCmd}, tc.args...)...)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
 
 			for _, expectedExpression := range tc.expectedExpressions {
 				if !strings.Contains(output, expectedExpression) {
@@ -120,3 +114,47 @@ func TestPowerShellCompletion(t *testing.T) {
 		})
 	}
 }
+
+func TestPowerShellCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "prog", Run: emptyRun}
+	rootCmd.AddCommand(&Command{Use: "sub", Run: emptyRun})
+
+	buf := new(bytes.Buffer)
+	if err := rootCmd.GenPowerShellCompletion(buf); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	checkStringContains(t, output, "Register-ArgumentCompleter -Native -CommandName 'prog' -ScriptBlock")
+	checkStringContains(t, output, fmt.Sprintf(`$RequestComp = "$Program %s $Arguments"`, CompRequestCmd))
+	checkStringContains(t, output, "function __prog_debug")
+	// The directives are those of the Go code
+	checkStringContains(t, output, fmt.Sprintf("$ShellCompDirectiveError = %d", BashCompDirectiveError))
+	checkStringContains(t, output, fmt.Sprintf("$ShellCompDirectiveNoSpace = %d", BashCompDirectiveNoSpace))
+	checkStringContains(t, output, fmt.Sprintf("$ShellCompDirectiveNoFileComp = %d", BashCompDirectiveNoFileComp))
+	checkStringContains(t, output, fmt.Sprintf("$ShellCompDirectiveFilterFileExt = %d", BashCompDirectiveFilterFileExt))
+	checkStringContains(t, output, fmt.Sprintf("$ShellCompDirectiveFilterDirs = %d", BashCompDirectiveFilterDirs))
+	checkStringContains(t, output, fmt.Sprintf("$ShellCompDirectiveTimeout = %d", BashCompDirectiveTimeout))
+	// The subcommands are not hardcoded in the script
+	checkStringOmits(t, output, "'sub'")
+}
+
+func TestPowerShellPositionalArgCompletion(t *testing.T) {
+	rootCmd := &Command{Use: "prog", Args: ArbitraryArgs, Run: emptyRun}
+	if err := rootCmd.MarkPositionalArgFilename(1, "*.yaml"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The script completes the files of the extensions requested through __complete
+	output, err := executeCommand(rootCmd, CompRequestCmd, "")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	checkStringContains(t, output, fmt.Sprintf("yaml\n:%d\n", BashCompDirectiveFilterFileExt))
+
+	buf := new(bytes.Buffer)
+	if err := rootCmd.GenPowerShellCompletion(buf); err != nil {
+		t.Fatal(err)
+	}
+	checkStringContains(t, buf.String(), `$File.Name -like "*.$_"`)
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"sort"
 	"strings"
 	"text/template"
@@ -99,13 +98,14 @@ type zshCompArgHint struct {
 
 // GenZshCompletionFile generates zsh completion file.
 func (c *Command) GenZshCompletionFile(filename string) error {
-	outFile, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
+	return c.GenZshCompletionFS(DirFS(""), filename)
+}
 
-	return c.GenZshCompletion(outFile)
+// GenZshCompletionFS generates the zsh completion file name in fs.
+func (c *Command) GenZshCompletionFS(fs WriteFS, name string) error {
+	return WriteFile(fs, name, func(w io.Writer) error {
+		return c.GenZshCompletion(w)
+	})
 }
 
 // GenZshCompletion generates a zsh completion file and writes to the passed
@@ -122,6 +122,9 @@ func (c *Command) GenZshCompletion(w io.Writer) error {
 // MarkZshCompPositionalArgumentFile marks the specified argument (first
 // argument is 1) as completed by file selection. patterns (e.g. "*.txt") are
 // optional - if not provided the completion will search for all files.
+//
+// Deprecated: only the V1 zsh script uses it, use MarkPositionalArgFilename,
+// which works for all shells.
 func (c *Command) MarkZshCompPositionalArgumentFile(argPosition int, patterns ...string) error {
 	if argPosition < 1 {
 		return fmt.Errorf("Invalid argument position (%d)", argPosition)
@@ -144,6 +147,9 @@ func (c *Command) MarkZshCompPositionalArgumentFile(argPosition int, patterns ..
 // (first argument is 1) as completed by the provided words. At east one word
 // must be provided, spaces within words will be offered completion with
 // "word\ word".
+//
+// Deprecated: only the V1 zsh script uses it, use MarkPositionalArgWords,
+// which works for all shells.
 func (c *Command) MarkZshCompPositionalArgumentWords(argPosition int, words ...string) error {
 	if argPosition < 1 {
 		return fmt.Errorf("Invalid argument position (%d)", argPosition)
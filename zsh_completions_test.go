@@ -72,7 +72,7 @@ func TestGenZshCompletion(t *testing.T) {
 				return r
 			}(),
 			expectedExpressions: []string{
-				`commands=\(\n\s+"help:.*\n\s+"subcmd1:.*\n\s+"subcmd2:.*\n\s+\)`,
+				`commands=\(\n\s+"completion:.*\n\s+"help:.*\n\s+"subcmd1:.*\n\s+"subcmd2:.*\n\s+\)`,
 				`_arguments \\\n.*'--debug\[description]'`,
 				`_arguments -C \\\n.*'--debug\[description]'`,
 				`function _rootcmd_subcmd1 {`,
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 )
 
 func genZshCompV2(buf *bytes.Buffer, name string, includeDesc bool) {
@@ -26,8 +25,8 @@ __%[1]s_debug()
 
 _%[1]s()
 {
-    local lastParam lastChar flagPrefix requestComp out directive compCount comp lastComp
-    local -a completions
+    local lastParam lastChar flagPrefix requestComp out directive compCount comp desc group i
+    local -a completions compGroups fields groupComps
 
     __%[1]s_debug "\n========= starting completion logic =========="
     __%[1]s_debug "CURRENT: ${CURRENT}, words[*]: ${words[*]}"
@@ -55,15 +54,19 @@ _%[1]s()
 
     __%[1]s_debug "About to call: eval ${requestComp}"
 
+    # Request the completions with their group and kind, in format 3
+    # where the fields are escaped
+    local -x COBRA_COMPLETION_FORMAT=3
+
     # Use eval to handle any environment variables and such
     out=$(eval ${requestComp} 2>/dev/null)
     __%[1]s_debug "completion output: ${out}"
 
     # Extract the directive integer following a : as the last line
-    if [ "${out[-2]}" = : ]; then
-        directive=${out[-1]}
-        # Remove the directive (that means the last 3 chars as we include the : and the newline)
-        out=${out[1,-4]}
+    directive=${out##*:}
+    if [[ "${out}" == *:* && "${directive}" == <-> ]]; then
+        # Remove the directive, including the :
+        out=${out%%:*}
     else
         # There is not directive specified.  Leave $out as is.
         __%[1]s_debug "No directive found.  Setting do default"
@@ -77,29 +80,74 @@ _%[1]s()
     if [ $((directive & %[3]d)) -ne 0 ]; then
         __%[1]s_debug "Completion received error. Ignoring completions."
     else
+        if [ $((directive & %[6]d)) -ne 0 ]; then
+            __%[1]s_debug "Completion timed out, using the completions gathered so far"
+        fi
+
+        if [ $((directive & %[7]d)) -ne 0 ]; then
+            # File extension filtering: the completion values are the extensions
+            local -a filters
+            for comp in "${(@f)out}"; do
+                if [ -n "$comp" ]; then
+                    filters+=("${(g::)${comp%%%%$'\t'*}}")
+                fi
+            done
+            local globs="${(j:|:)filters}"
+            __%[1]s_debug "File filtering command: _files -g \"*.(${globs})\""
+            _files -g "*.(${globs})"
+            return
+        elif [ $((directive & %[8]d)) -ne 0 ]; then
+            # File completion for directories only
+            local subdir="${(g::)${out%%%%$'\t'*}}"
+            if [ -n "$subdir" ]; then
+                __%[1]s_debug "Listing directories in $subdir"
+                _files -/ -W "$subdir"
+            else
+                __%[1]s_debug "Listing directories in ."
+                _files -/
+            fi
+            return
+        fi
+
+        local -a nospaceOpt
+        if [ $((directive & %[4]d)) -ne 0 ]; then
+            __%[1]s_debug "Activating nospace."
+            nospaceOpt=(-S '')
+        fi
+
         compCount=0
-        while IFS='\n' read -r comp; do
+        while IFS='' read -r comp; do
             if [ -n "$comp" ]; then
                 ((compCount++))
+                # Each completion holds its value, description, group and kind separated by tabs.
+                # Within each field, backslashes, tabs and newlines are escaped.
+                fields=("${(@ps:\t:)comp}")
+                comp=${(g::)fields[1]}
+                desc=${(g::)fields[2]}
+                group=${(g::)fields[3]}
+                # Descriptions are shown on a single line
+                desc=${desc//$'\n'/ }
+                desc=${desc//$'\t'/ }
                 if [ -n "$flagPrefix" ]; then
                     # We use compadd here so that we can hide the flagPrefix from the list
                     # of choices. We can use compadd because there is no description in this case.
-                    __%[1]s_debug "Calling: compadd -p ${flagPrefix} ${comp}"
-                    compadd -p ${flagPrefix} ${comp}
+                    # compadd quotes the value for the command-line.
+                    __%[1]s_debug "Calling: compadd ${nospaceOpt[*]} -p ${flagPrefix} ${comp}"
+                    compadd "${nospaceOpt[@]}" -p "${flagPrefix}" -- "${comp}"
                 else
-                    # If requested, completions are returned with a description.
-                    # The description is preceded by a TAB character.
-                    # For zsh's _describe, we need to use a : instead of a TAB.
-                    # We first need to escape any : as part of the completion itself.
+                    # For zsh's _describe, the description follows a :
+                    # We first need to escape any \ and : as part of the completion itself.
+                    # _describe quotes the value for the command-line.
+                    comp=${comp//\\/\\\\}
                     comp=${comp//:/\\:}
+                    if [ -n "$desc" ]; then
+                        comp="${comp}:${desc}"
+                    fi
 
-                    local tab=$(printf '\t')
-                    comp=${comp//$tab/:}
-
-                    __%[1]s_debug "Adding completion: ${comp}"
-                    completions+=${comp}
+                    __%[1]s_debug "Adding completion: ${comp} (group: ${group})"
+                    completions+=("${comp}")
+                    compGroups+=("${group}")
                 fi
-                lastComp=$comp
             fi
         done < <(printf "%%s\n" "${out[@]}")
 
@@ -111,19 +159,25 @@ _%[1]s()
                 __%[1]s_debug "activating file completion"
                 _arguments '*:filename:_files'
             fi
-        elif [ $((directive & %[4]d)) -ne 0 ] && [ ${compCount} -eq 1 ]; then
-            __%[1]s_debug "Activating nospace."
-            # We can use compadd here as there is no description when
-            # there is only one completion.
-            compadd -S '' "${lastComp}"
         else
-            _describe "completions" completions
+            # Each group of completions is listed separately
+            for group in "${(@u)compGroups}"; do
+                groupComps=()
+                for ((i = 1; i <= ${#completions}; i++)); do
+                    if [ "${compGroups[$i]}" = "${group}" ]; then
+                        groupComps+=("${completions[$i]}")
+                    fi
+                done
+                # Options following the array are passed to compadd
+                _describe -t "${group:-completions}" "${group:-completions}" groupComps "${nospaceOpt[@]}"
+            done
         fi
     fi
 }
 
 compdef _%[1]s %[1]s
-`, name, compCmd, BashCompDirectiveError, BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp))
+`, name, compCmd, BashCompDirectiveError, BashCompDirectiveNoSpace, BashCompDirectiveNoFileComp, BashCompDirectiveTimeout,
+		BashCompDirectiveFilterFileExt, BashCompDirectiveFilterDirs))
 }
 
 // GenZshCompletionV2 generates the zsh completion V2 file and writes to the passed writer.
@@ -136,11 +190,12 @@ func (c *Command) GenZshCompletionV2(w io.Writer, includeDesc bool) error {
 
 // GenZshCompletionFileV2 generates the zsh completion V2 file.
 func (c *Command) GenZshCompletionFileV2(filename string, includeDesc bool) error {
-	outFile, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
+	return c.GenZshCompletionFSV2(DirFS(""), filename, includeDesc)
+}
 
-	return c.GenZshCompletionV2(outFile, includeDesc)
+// GenZshCompletionFSV2 generates the zsh completion V2 file name in fs.
+func (c *Command) GenZshCompletionFSV2(fs WriteFS, name string, includeDesc bool) error {
+	return WriteFile(fs, name, func(w io.Writer) error {
+		return c.GenZshCompletionV2(w, includeDesc)
+	})
 }